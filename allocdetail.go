@@ -0,0 +1,230 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// ioctl.h: alloc detail entry meta bit
+	allocFlagMeta = 0x1
+	// ioctl.h: alloc detail entry avail bit
+	allocFlagAvail = 0x2
+	// defaultMetaBytesPerInode is the metadata used per inode when there
+	// are no inodes to measure it from
+	defaultMetaBytesPerInode = 1024
+	// metaBlockSize is the size of metadata blocks
+	metaBlockSize = 64 * 1024
+)
+
+// AllocOwner is the owner of an allocator
+type AllocOwner uint8
+
+const (
+	// format.h: SCOUTFS_ALLOC_OWNER_NONE
+	AllocOwnerNone AllocOwner = 0
+	// format.h: SCOUTFS_ALLOC_OWNER_SERVER
+	AllocOwnerServer AllocOwner = 1
+	// format.h: SCOUTFS_ALLOC_OWNER_MOUNT
+	AllocOwnerMount AllocOwner = 2
+	// format.h: SCOUTFS_ALLOC_OWNER_SRCH
+	AllocOwnerSrch AllocOwner = 3
+	// format.h: SCOUTFS_ALLOC_OWNER_LOG_MERGE
+	AllocOwnerLogMerge AllocOwner = 4
+)
+
+func (o AllocOwner) String() string {
+	switch o {
+	case AllocOwnerNone:
+		return "none"
+	case AllocOwnerServer:
+		return "server"
+	case AllocOwnerMount:
+		return "mount"
+	case AllocOwnerSrch:
+		return "srch"
+	case AllocOwnerLogMerge:
+		return "log_merge"
+	}
+	return fmt.Sprintf("owner(%d)", uint8(o))
+}
+
+// AllocEntry is the free blocks in one allocator
+type AllocEntry struct {
+	// ID identifies the owner, such as the rid of a mount
+	ID     uint64
+	Blocks uint64
+	Owner  AllocOwner
+	// Meta is set for metadata allocators, otherwise data
+	Meta bool
+	// Avail is set for blocks available for allocation, otherwise the
+	// blocks were freed and are not yet available
+	Avail bool
+}
+
+// AllocDetail returns every allocator in the filesystem
+func AllocDetail(f *os.File) ([]AllocEntry, error) {
+	nr := dfBatchCount
+	buf := make([]byte, int(unsafe.Sizeof(allocDetailEntry{}))*int(nr))
+	var ret int
+	var err error
+	for {
+		ad := allocDetail{
+			Nr:  nr,
+			Ptr: uint64(uintptr(unsafe.Pointer(&buf[0]))),
+		}
+		ret, err = scoutfsctl(f, IOCALLOCDETAIL, unsafe.Pointer(&ad))
+		if err == syscall.EOVERFLOW {
+			nr = nr * 2
+			buf = make([]byte, int(unsafe.Sizeof(allocDetailEntry{}))*int(nr))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("alloc detail: %v", err)
+		}
+		break
+	}
+
+	rbuf := bytes.NewReader(buf)
+	entries := make([]AllocEntry, ret)
+	var ade allocDetailEntry
+	for i := 0; i < ret; i++ {
+		err := binary.Read(rbuf, binary.LittleEndian, &ade)
+		if err != nil {
+			return nil, fmt.Errorf("parse alloc detail results: %v", err)
+		}
+		entries[i] = AllocEntry{
+			ID:     ade.Id,
+			Blocks: ade.Blocks,
+			Owner:  AllocOwner(ade.Type),
+			Meta:   ade.Flags&allocFlagMeta != 0,
+			Avail:  ade.Flags&allocFlagAvail != 0,
+		}
+	}
+
+	return entries, nil
+}
+
+// AllocPool is the free blocks of all the allocators of one owner
+type AllocPool struct {
+	Owner      AllocOwner
+	ID         uint64
+	MetaAvail  uint64
+	MetaFreed  uint64
+	DataAvail  uint64
+	DataFreed  uint64
+	Allocators int
+}
+
+// AllocPools groups allocator entries by owner, sorted by owner and id
+func AllocPools(entries []AllocEntry) []AllocPool {
+	type key struct {
+		owner AllocOwner
+		id    uint64
+	}
+	pools := make(map[key]*AllocPool)
+	for _, e := range entries {
+		k := key{e.Owner, e.ID}
+		p, ok := pools[k]
+		if !ok {
+			p = &AllocPool{Owner: e.Owner, ID: e.ID}
+			pools[k] = p
+		}
+		p.Allocators++
+		switch {
+		case e.Meta && e.Avail:
+			p.MetaAvail += e.Blocks
+		case e.Meta:
+			p.MetaFreed += e.Blocks
+		case e.Avail:
+			p.DataAvail += e.Blocks
+		default:
+			p.DataFreed += e.Blocks
+		}
+	}
+
+	ret := make([]AllocPool, 0, len(pools))
+	for _, p := range pools {
+		ret = append(ret, *p)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Owner != ret[j].Owner {
+			return ret[i].Owner < ret[j].Owner
+		}
+		return ret[i].ID < ret[j].ID
+	})
+	return ret
+}
+
+func percent(part, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
+
+// UsedMetaBlocks returns the metadata blocks in use
+func (d DiskUsage) UsedMetaBlocks() uint64 {
+	if d.FreeMetaBlocks > d.TotalMetaBlocks {
+		return 0
+	}
+	return d.TotalMetaBlocks - d.FreeMetaBlocks
+}
+
+// UsedDataBlocks returns the data blocks in use
+func (d DiskUsage) UsedDataBlocks() uint64 {
+	if d.FreeDataBlocks > d.TotalDataBlocks {
+		return 0
+	}
+	return d.TotalDataBlocks - d.FreeDataBlocks
+}
+
+// MetaUsedPercent returns the percentage of metadata blocks in use
+func (d DiskUsage) MetaUsedPercent() float64 {
+	return percent(d.UsedMetaBlocks(), d.TotalMetaBlocks)
+}
+
+// MetaFreePercent returns the percentage of metadata blocks free
+func (d DiskUsage) MetaFreePercent() float64 {
+	return percent(d.FreeMetaBlocks, d.TotalMetaBlocks)
+}
+
+// DataUsedPercent returns the percentage of data blocks in use
+func (d DiskUsage) DataUsedPercent() float64 {
+	return percent(d.UsedDataBlocks(), d.TotalDataBlocks)
+}
+
+// DataFreePercent returns the percentage of data blocks free
+func (d DiskUsage) DataFreePercent() float64 {
+	return percent(d.FreeDataBlocks, d.TotalDataBlocks)
+}
+
+// EstimateFreeInodes estimates the number of inodes that could still be
+// created from the free metadata blocks outside the reserve.  The
+// metadata used per inode is measured from the number of inodes in use,
+// such as the count from a meta_seq Query, or a default when 0.
+func (d DiskUsage) EstimateFreeInodes(inodes uint64) uint64 {
+	perInode := uint64(defaultMetaBytesPerInode)
+	if inodes > 0 && d.UsedMetaBlocks() > 0 {
+		perInode = d.UsedMetaBlocks() * metaBlockSize / inodes
+		if perInode == 0 {
+			perInode = 1
+		}
+	}
+
+	if d.FreeMetaBlocks <= d.ReservedMetaBlocks {
+		return 0
+	}
+	return (d.FreeMetaBlocks - d.ReservedMetaBlocks) * metaBlockSize / perInode
+}
@@ -0,0 +1,514 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// ArchiveXattr is the hidden xattr recording where the contents of
+	// a file were archived
+	ArchiveXattr = "scoutfs.hide.archive"
+	// archiveBufsize is the default copy size for archive and restore
+	archiveBufsize = 1024 * 1024
+	// manifestSuffix is appended to the archive copy for its checksums
+	manifestSuffix = ".sums"
+	// waitersBackoff is the first delay before serving waiters again
+	// for files that were already served
+	waitersBackoff = 10 * time.Millisecond
+)
+
+// ArchiveInfo is the archive record stored with an archived file
+type ArchiveInfo struct {
+	// Version is the data version of the archived contents
+	Version uint64
+	// Location is the backend specific location of the archived contents
+	Location string
+}
+
+// String returns the xattr value representation of ArchiveInfo
+func (a ArchiveInfo) String() string {
+	return fmt.Sprintf("%v:%v", a.Version, a.Location)
+}
+
+func parseArchiveInfo(b []byte) (ArchiveInfo, error) {
+	s := string(b)
+	i := strings.IndexByte(s, ':')
+	if i == -1 {
+		return ArchiveInfo{}, fmt.Errorf("invalid archive record %q", s)
+	}
+	version, err := strconv.ParseUint(s[:i], 10, 64)
+	if err != nil {
+		return ArchiveInfo{}, fmt.Errorf("invalid archive version %q: %v", s, err)
+	}
+	return ArchiveInfo{
+		Version:  version,
+		Location: s[i+1:],
+	}, nil
+}
+
+// GetArchiveInfo returns the archive record for file handle
+// syscall.ENODATA is returned if the file has not been archived
+func GetArchiveInfo(f *os.File) (ArchiveInfo, error) {
+	b := make([]byte, pathmax+32)
+	n, err := fgetxattr(f, ArchiveXattr, b)
+	if err != nil {
+		return ArchiveInfo{}, err
+	}
+	return parseArchiveInfo(b[:n])
+}
+
+// SetArchiveInfo records the archive record for file handle
+func SetArchiveInfo(f *os.File, info ArchiveInfo) error {
+	return fsetxattr(f, ArchiveXattr, []byte(info.String()), 0)
+}
+
+// LocalArchive is a reference copytool that archives file contents
+// to a local directory.  Archived contents are stored at
+// <dir>/<fsid>/<ino>/<data_version> and the location is recorded in the
+// ArchiveXattr of the file.  If checksums are enabled the chunk manifest
+// is stored alongside the archived contents and verified on restore.
+type LocalArchive struct {
+	dir       string
+	fsfd      *os.File
+	fsid      uint64
+	bufsize   int
+	errfunc   func(ino uint64, err error)
+	interval  time.Duration
+	checksum  *ChecksumAlgorithm
+	chunkSize uint64
+}
+
+// AOption sets various options for NewLocalArchive
+type AOption func(*LocalArchive)
+
+// WithArchiveBufSize sets the copy buffer size, rounded up to 4KB
+func WithArchiveBufSize(size int) AOption {
+	return func(a *LocalArchive) {
+		a.bufsize = int(divRoundUp(uint64(size), scoutfsBS))
+	}
+}
+
+// WithArchiveChecksum records a chunk manifest for archived contents
+// using the algorithm and chunk size, a chunk size of 0 uses the default
+func WithArchiveChecksum(alg ChecksumAlgorithm, chunkSize uint64) AOption {
+	return func(a *LocalArchive) {
+		if chunkSize == 0 {
+			chunkSize = defaultChunkSize
+		}
+		a.checksum = &alg
+		a.chunkSize = chunkSize
+	}
+}
+
+// WithRestoreErrorFunc sets a callback for restore failures while serving
+// data waiters
+func WithRestoreErrorFunc(fn func(ino uint64, err error)) AOption {
+	return func(a *LocalArchive) {
+		a.errfunc = fn
+	}
+}
+
+// WithWaitersInterval sets the polling interval for data waiters when
+// there are no waiters
+func WithWaitersInterval(d time.Duration) AOption {
+	return func(a *LocalArchive) {
+		a.interval = d
+	}
+}
+
+// NewLocalArchive creates a local directory archive for the filesystem
+// An open file within scoutfs is supplied for ioctls
+// (usually just the base mount point directory)
+func NewLocalArchive(fsfd *os.File, dir string, opts ...AOption) (*LocalArchive, error) {
+	id, err := GetIDs(fsfd)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &LocalArchive{
+		dir:      dir,
+		fsfd:     fsfd,
+		fsid:     id.FSID,
+		bufsize:  archiveBufsize,
+		interval: time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if a.bufsize == 0 {
+		a.bufsize = archiveBufsize
+	}
+	if a.checksum != nil && a.chunkSize%scoutfsBS != 0 {
+		return nil, fmt.Errorf("chunk size %v not a multiple of %v",
+			a.chunkSize, scoutfsBS)
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("create archive dir: %v", err)
+	}
+
+	return a, nil
+}
+
+func (a *LocalArchive) location(ino, version uint64) string {
+	return filepath.Join(fmt.Sprintf("%016x", a.fsid),
+		strconv.FormatUint(ino, 10), strconv.FormatUint(version, 10))
+}
+
+// Archive copies the contents of file handle to the archive and records
+// the archive location with the file.  The file must not have offline
+// extents.
+func (a *LocalArchive) Archive(f *os.File) (ArchiveInfo, error) {
+	st, err := FStatMore(f)
+	if err != nil {
+		return ArchiveInfo{}, fmt.Errorf("stat more: %v", err)
+	}
+	if st.Offline_blocks != 0 {
+		return ArchiveInfo{}, fmt.Errorf("file has %v offline blocks",
+			st.Offline_blocks)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return ArchiveInfo{}, err
+	}
+	ino, err := fileIno(fi)
+	if err != nil {
+		return ArchiveInfo{}, err
+	}
+
+	info := ArchiveInfo{
+		Version:  st.Data_version,
+		Location: a.location(ino, st.Data_version),
+	}
+
+	dst := filepath.Join(a.dir, info.Location)
+	err = a.copyOut(f, fi.Size(), dst)
+	if err != nil {
+		return ArchiveInfo{}, err
+	}
+
+	st, err = FStatMore(f)
+	if err != nil {
+		return ArchiveInfo{}, fmt.Errorf("stat more: %v", err)
+	}
+	if st.Data_version != info.Version {
+		os.Remove(dst)
+		os.Remove(dst + manifestSuffix)
+		return ArchiveInfo{}, fmt.Errorf("file changed during archive: version %v != %v",
+			st.Data_version, info.Version)
+	}
+
+	err = SetArchiveInfo(f, info)
+	if err != nil {
+		return ArchiveInfo{}, fmt.Errorf("set archive xattr: %v", err)
+	}
+
+	return info, nil
+}
+
+func (a *LocalArchive) copyOut(f *os.File, size int64, dst string) error {
+	err := os.MkdirAll(filepath.Dir(dst), 0755)
+	if err != nil {
+		return fmt.Errorf("create archive dir: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".archive-")
+	if err != nil {
+		return fmt.Errorf("create archive file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	var w io.Writer = tmp
+	var hasher *ChunkHasher
+	if a.checksum != nil {
+		hasher, err = NewChunkHasher(*a.checksum, a.chunkSize)
+		if err != nil {
+			return err
+		}
+		w = io.MultiWriter(tmp, hasher)
+	}
+
+	buf := make([]byte, a.bufsize)
+	_, err = io.CopyBuffer(w, io.NewSectionReader(f, 0, size), buf)
+	if err != nil {
+		return fmt.Errorf("copy to archive: %v", err)
+	}
+
+	if hasher != nil {
+		err = WriteChunkManifest(dst+manifestSuffix, hasher.Manifest())
+		if err != nil {
+			return fmt.Errorf("write manifest: %v", err)
+		}
+	} else {
+		os.Remove(dst + manifestSuffix)
+	}
+
+	err = tmp.Sync()
+	if err != nil {
+		return fmt.Errorf("sync archive file: %v", err)
+	}
+
+	err = os.Rename(tmp.Name(), dst)
+	if err != nil {
+		return fmt.Errorf("rename archive file: %v", err)
+	}
+
+	return nil
+}
+
+// Release sets file handle offline after verifying that the current
+// contents have been archived
+func (a *LocalArchive) Release(f *os.File) error {
+	info, err := GetArchiveInfo(f)
+	if err != nil {
+		return fmt.Errorf("get archive info: %v", err)
+	}
+
+	st, err := FStatMore(f)
+	if err != nil {
+		return fmt.Errorf("stat more: %v", err)
+	}
+	if st.Data_version != info.Version {
+		return fmt.Errorf("archive is stale: version %v != %v",
+			info.Version, st.Data_version)
+	}
+
+	_, err = os.Stat(filepath.Join(a.dir, info.Location))
+	if err != nil {
+		return fmt.Errorf("archive copy: %v", err)
+	}
+
+	return FReleaseFile(f, st.Data_version)
+}
+
+// Restore stages the archived contents of file handle back online
+// The file handle must be opened for writing.
+func (a *LocalArchive) Restore(f *os.File) error {
+	st, err := FStatMore(f)
+	if err != nil {
+		return fmt.Errorf("stat more: %v", err)
+	}
+	if st.Offline_blocks == 0 {
+		return nil
+	}
+
+	info, err := GetArchiveInfo(f)
+	if err != nil {
+		return fmt.Errorf("get archive info: %v", err)
+	}
+	if st.Data_version != info.Version {
+		return fmt.Errorf("archive is stale: version %v != %v",
+			info.Version, st.Data_version)
+	}
+
+	path := filepath.Join(a.dir, info.Location)
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open archive copy: %v", err)
+	}
+	defer src.Close()
+
+	bufsize := uint64(a.bufsize)
+	m, err := ReadChunkManifest(path + manifestSuffix)
+	if err != nil && (a.checksum != nil || !os.IsNotExist(err)) {
+		return fmt.Errorf("read manifest: %v", err)
+	}
+	if m != nil {
+		// verification needs whole chunks per stage
+		bufsize = divRoundUp(bufsize, m.ChunkSize)
+	}
+
+	buf := make([]byte, bufsize)
+	var off int64
+	for {
+		n, err := src.ReadAt(buf, off)
+		if m != nil && uint64(n)%m.ChunkSize != 0 &&
+			uint64(off)+uint64(n) < m.Size {
+			// a truncated copy ends within a chunk, fail the size below
+			break
+		}
+		if n > 0 {
+			var staged int
+			var serr error
+			if m != nil {
+				staged, serr = FStageFileVerified(a.fsfd, f, m,
+					info.Version, uint64(off), buf[:n])
+			} else {
+				staged, serr = FStageFile(f, info.Version, uint64(off), buf[:n])
+			}
+			if serr != nil {
+				return fmt.Errorf("stage offset %v: %w", off, serr)
+			}
+			if staged != n {
+				return fmt.Errorf("stage offset %v: short stage %v != %v",
+					off, staged, n)
+			}
+			off += int64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read archive copy: %v", err)
+		}
+	}
+
+	if m != nil && uint64(off) != m.Size {
+		// the copy is truncated or longer than the manifest
+		var length uint64
+		if m.Size > uint64(off) {
+			length = m.Size - uint64(off)
+		}
+		return fmt.Errorf("archive copy size %v: %w", off,
+			failDataWaiters(a.fsfd, f, info.Version, uint64(off), length,
+				&ChecksumError{Offset: uint64(off), Length: length}))
+	}
+
+	return nil
+}
+
+// RestoreIno stages the archived contents of inode back online
+func (a *LocalArchive) RestoreIno(ino uint64) error {
+	f, err := OpenByID(a.fsfd, ino, os.O_WRONLY, "")
+	if err != nil {
+		return fmt.Errorf("open inode %v: %v", ino, err)
+	}
+	defer f.Close()
+
+	return a.Restore(f)
+}
+
+// ServeWaiters restores files that have tasks waiting on offline data
+// until the context is canceled.  Waiters for files that can not be
+// restored are sent EIO.  If the only waiters are for files that were
+// already served the next pass is delayed, backing off up to the waiters
+// interval.
+func (a *LocalArchive) ServeWaiters(ctx context.Context) error {
+	w := NewWaiters(a.fsfd)
+	served := make(map[uint64]struct{})
+	var backoff time.Duration
+
+	for {
+		w.Reset()
+
+		waiting := make(map[uint64][]DataWaitingEntry)
+		var inodes []uint64
+		for {
+			ents, err := w.Next()
+			if err != nil {
+				return fmt.Errorf("data waiting: %v", err)
+			}
+			if ents == nil {
+				break
+			}
+			for _, e := range ents {
+				if _, ok := waiting[e.Ino]; !ok {
+					inodes = append(inodes, e.Ino)
+				}
+				waiting[e.Ino] = append(waiting[e.Ino], e)
+			}
+		}
+
+		progress := false
+		for _, ino := range inodes {
+			if _, ok := served[ino]; !ok {
+				progress = true
+			}
+		}
+
+		var delay time.Duration
+		switch {
+		case len(inodes) == 0:
+			served = make(map[uint64]struct{})
+			backoff = 0
+			delay = a.interval
+		case progress:
+			backoff = 0
+		default:
+			// waiters remain on files already served
+			if backoff == 0 {
+				backoff = waitersBackoff
+			} else {
+				backoff *= 2
+			}
+			if backoff > a.interval {
+				backoff = a.interval
+			}
+			delay = backoff
+		}
+
+		if delay > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			if len(inodes) == 0 {
+				continue
+			}
+		}
+
+		served = make(map[uint64]struct{}, len(inodes))
+		for _, ino := range inodes {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			served[ino] = struct{}{}
+			err := a.serveWaiting(ino, waiting[ino])
+			if err != nil && a.errfunc != nil {
+				a.errfunc(ino, err)
+			}
+		}
+	}
+}
+
+func (a *LocalArchive) serveWaiting(ino uint64, ents []DataWaitingEntry) error {
+	err := a.RestoreIno(ino)
+	if err == nil {
+		return nil
+	}
+
+	var version uint64
+	f, oerr := OpenByID(a.fsfd, ino, os.O_RDONLY, "")
+	if oerr == nil {
+		st, serr := FStatMore(f)
+		if serr == nil {
+			version = st.Data_version
+		}
+		f.Close()
+	}
+
+	for _, e := range ents {
+		SendDataWaitErr(a.fsfd, ino, version, e.Iblock*scoutfsBS,
+			uint64(e.Op), scoutfsBS, -int64(syscall.EIO))
+	}
+
+	return err
+}
+
+func fileIno(fi os.FileInfo) (uint64, error) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("unsupported stat type %T", fi.Sys())
+	}
+	return st.Ino, nil
+}
@@ -0,0 +1,538 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+// Package catalog maintains an index of the inodes in a scoutfs
+// filesystem for fast find style queries.  The catalog is kept current by
+// consuming the meta_seq index, so each update only visits the inodes
+// changed since the last update.  Entries and their secondary indices are
+// held in memory and persisted in the catalog directory as a snapshot and
+// a journal of the changes made since the snapshot.
+package catalog
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	scoutfs "github.com/versity/scoutfs-go"
+)
+
+const (
+	max32 = 0xffffffff
+	max64 = 0xffffffffffffffff
+
+	// rootIno is the inode number of the filesystem root
+	rootIno = 1
+	// defaultBatch is the default number of inodes per meta_seq query
+	defaultBatch = 1024
+	// linksBufsize is the buffer size for reading the links of an inode
+	linksBufsize = 4096
+	// compactRecords is the journal length that triggers a compaction
+	// once it also exceeds the number of entries
+	compactRecords = 64 * 1024
+)
+
+// Link is a path referring to a cataloged inode
+type Link struct {
+	// Parent is the inode of the directory containing the entry
+	Parent uint64
+	// Path is relative to the filesystem root
+	Path string
+}
+
+// Entry is the cataloged state of an inode
+type Entry struct {
+	Ino uint64
+	// Parent is the inode of the directory containing Path
+	Parent uint64
+	// Path is the first link to the inode relative to the filesystem
+	// root, the root has an empty path
+	Path string
+	// Links holds every link to the inode, starting with Path
+	Links         []Link
+	Mode          os.FileMode
+	UID           uint32
+	GID           uint32
+	Size          int64
+	Mtime         time.Time
+	Crtime        time.Time
+	MetaSeq       uint64
+	DataSeq       uint64
+	DataVersion   uint64
+	OnlineBlocks  uint64
+	OfflineBlocks uint64
+	// Xattrs are the names of the xattrs, including hidden xattrs
+	Xattrs []string
+}
+
+// UpdateStats counts the changes made by an update
+type UpdateStats struct {
+	Scanned uint64
+	Updated uint64
+	Deleted uint64
+}
+
+// Catalog is an on-disk index of a scoutfs filesystem
+type Catalog struct {
+	mu     sync.RWMutex
+	mnt    string
+	fsfd   *os.File
+	batch  uint32
+	cursor scoutfs.InodesEntry
+	idx    *index
+	store  *store
+	xbuf   []byte
+	// resolver resolves the paths of the parents of changed inodes
+	resolver *scoutfs.PathResolver
+	// recheck holds inodes whose path was taken by another inode
+	recheck []uint64
+}
+
+// Option sets various options for Open
+type Option func(*Catalog)
+
+// WithBatchSize sets the number of inodes read per meta_seq query
+func WithBatchSize(size uint32) Option {
+	return func(c *Catalog) {
+		c.batch = size
+	}
+}
+
+// Open opens the catalog stored in dir for the scoutfs filesystem mounted
+// at mnt, creating an empty catalog if dir has none.  Call Update to bring
+// the catalog up to date with the filesystem.
+func Open(dir, mnt string, opts ...Option) (*Catalog, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	fsfd, err := os.Open(mnt)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{
+		mnt:      mnt,
+		fsfd:     fsfd,
+		batch:    defaultBatch,
+		idx:      newIndex(),
+		store:    &store{dir: dir},
+		resolver: scoutfs.NewPathResolver(fsfd),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.cursor, err = c.store.load(c.idx.put, c.idx.remove)
+	if err == nil {
+		err = c.store.open()
+	}
+	if err != nil {
+		fsfd.Close()
+		return nil, fmt.Errorf("open catalog %q: %v", dir, err)
+	}
+
+	return c, nil
+}
+
+// Close writes any journaled changes and closes the catalog
+func (c *Catalog) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := c.store.close()
+	c.fsfd.Close()
+	return err
+}
+
+// Len returns the number of cataloged inodes
+func (c *Catalog) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.idx.entries)
+}
+
+// Get returns the entry for ino
+func (c *Catalog) Get(ino uint64) (Entry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.idx.entries[ino]
+	if !ok {
+		return Entry{}, false
+	}
+	return *e, true
+}
+
+// Lookup returns the entry for path relative to the filesystem root, any
+// link of an inode with multiple links finds its entry
+func (c *Catalog) Lookup(p string) (Entry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ino, ok := c.idx.byPath[cleanPrefix(p)]
+	if !ok {
+		return Entry{}, false
+	}
+	return *c.idx.entries[ino], true
+}
+
+// Find returns the entries matching q sorted by path.  The query is driven
+// by whichever index selects the fewest entries.
+func (c *Catalog) Find(q Query) []Entry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.idx.find(q)
+}
+
+// Rebuild discards the catalog and rebuilds it from every inode in the
+// filesystem
+func (c *Catalog) Rebuild() (UpdateStats, error) {
+	c.mu.Lock()
+	c.idx = newIndex()
+	c.cursor = scoutfs.InodesEntry{}
+	c.recheck = nil
+	err := c.store.compact(c.cursor, nil)
+	c.mu.Unlock()
+	if err != nil {
+		return UpdateStats{}, err
+	}
+
+	return c.Update()
+}
+
+// Update catalogs the inodes changed since the last update.  Deleted
+// inodes are found by checking the cataloged children of changed
+// directories, and renamed directories have the paths below them
+// rewritten.
+func (c *Catalog) Update() (UpdateStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var stats UpdateStats
+	max := scoutfs.InodesEntry{Major: max64, Minor: max32, Ino: max64}
+	q := scoutfs.NewQuery(c.fsfd, scoutfs.ByMSeq(c.cursor, max),
+		scoutfs.WithBatchSize(c.batch))
+
+	for {
+		ents, err := q.Next()
+		if err != nil {
+			return stats, fmt.Errorf("query meta_seq: %v", err)
+		}
+		if len(ents) == 0 {
+			break
+		}
+
+		// renamed directories have changed meta_seq
+		c.resolver.InvalidateEntries(ents)
+		for _, e := range ents {
+			stats.Scanned++
+			err = c.refresh(e.Ino, true, &stats)
+			if err != nil {
+				return stats, err
+			}
+		}
+		for len(c.recheck) > 0 {
+			ino := c.recheck[0]
+			c.recheck = c.recheck[1:]
+			err = c.refresh(ino, false, &stats)
+			if err != nil {
+				return stats, err
+			}
+		}
+
+		c.cursor = ents[len(ents)-1].Increment()
+		err = c.store.setCursor(c.cursor)
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	err := c.store.sync()
+	if err != nil {
+		return stats, err
+	}
+
+	if c.store.records > compactRecords && c.store.records > len(c.idx.entries) {
+		err = c.store.compact(c.cursor, c.idx.all())
+	}
+	return stats, err
+}
+
+// errGone is returned by stat for inodes that no longer have a path
+var errGone = fmt.Errorf("inode has no path")
+
+// refresh catalogs the current state of ino, removing it if it no longer
+// exists.  Directories have their cataloged children checked if reconcile
+// is set.
+func (c *Catalog) refresh(ino uint64, reconcile bool, stats *UpdateStats) error {
+	e, f, err := c.stat(ino)
+	if err == errGone {
+		if _, ok := c.idx.entries[ino]; ok {
+			stats.Deleted++
+			return c.removeTree(ino)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if f != nil {
+		defer f.Close()
+	}
+
+	var oldPath string
+	old, existed := c.idx.entries[ino]
+	if existed {
+		oldPath = old.Path
+	}
+	for _, l := range e.Links {
+		if other, ok := c.idx.byPath[l.Path]; ok && other != ino {
+			// replaced by rename or the other inode moved
+			c.recheck = append(c.recheck, other)
+		}
+	}
+
+	c.idx.put(e)
+	stats.Updated++
+	err = c.store.putEntry(e)
+	if err != nil {
+		return err
+	}
+
+	if e.Mode.IsDir() && existed && oldPath != e.Path {
+		err = c.rewritePaths(ino, e.Path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if reconcile && e.Mode.IsDir() && f != nil {
+		return c.reconcileDir(ino, f, stats)
+	}
+	return nil
+}
+
+// stat returns the entry for ino along with the open file when it could
+// be opened by handle
+func (c *Catalog) stat(ino uint64) (Entry, *os.File, error) {
+	e := Entry{Ino: ino, Parent: ino, Links: []Link{{Parent: ino}}}
+	if ino != rootIno {
+		links, err := c.links(ino)
+		if err != nil {
+			return Entry{}, nil, err
+		}
+		e.Links = links
+		e.Parent = links[0].Parent
+		e.Path = links[0].Path
+	}
+
+	var f *os.File
+	var fi os.FileInfo
+	fd, err := scoutfs.OpenByHandle(c.fsfd, ino,
+		syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_NOFOLLOW)
+	switch err {
+	case nil:
+		f = os.NewFile(fd, filepath.Join(c.mnt, e.Path))
+		fi, err = f.Stat()
+	case syscall.ENOENT, syscall.ESTALE:
+		return Entry{}, nil, errGone
+	default:
+		// symlinks and others that can't be opened are stat'd by path
+		fi, err = os.Lstat(filepath.Join(c.mnt, e.Path))
+		if os.IsNotExist(err) {
+			return Entry{}, nil, errGone
+		}
+	}
+	if err != nil {
+		if f != nil {
+			f.Close()
+		}
+		return Entry{}, nil, fmt.Errorf("stat inode %v: %v", ino, err)
+	}
+
+	e.Mode = fi.Mode()
+	e.Size = fi.Size()
+	e.Mtime = fi.ModTime()
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		e.UID = st.Uid
+		e.GID = st.Gid
+	}
+
+	if f != nil {
+		sm, err := scoutfs.FStatMore(f)
+		if err != nil {
+			f.Close()
+			return Entry{}, nil, fmt.Errorf("stat more inode %v: %v", ino, err)
+		}
+		e.MetaSeq = sm.Meta_seq
+		e.DataSeq = sm.Data_seq
+		e.DataVersion = sm.Data_version
+		e.OnlineBlocks = sm.Online_blocks
+		e.OfflineBlocks = sm.Offline_blocks
+		e.Crtime = time.Unix(int64(sm.Crtime_sec), int64(sm.Crtime_nsec))
+
+		lxr := scoutfs.NewListXattrHidden(f, c.xattrBuf())
+		for {
+			names, err := lxr.Next()
+			if err != nil {
+				f.Close()
+				return Entry{}, nil, fmt.Errorf("list xattrs inode %v: %v", ino, err)
+			}
+			if names == nil {
+				break
+			}
+			e.Xattrs = append(e.Xattrs, names...)
+		}
+	}
+
+	return e, f, nil
+}
+
+func (c *Catalog) xattrBuf() []byte {
+	if c.xbuf == nil {
+		c.xbuf = make([]byte, 256*1024)
+	}
+	return c.xbuf
+}
+
+// links returns every link to ino, errGone if it has none
+func (c *Catalog) links(ino uint64) ([]Link, error) {
+	var links []Link
+	l := scoutfs.LinksOf(c.fsfd, ino, scoutfs.WithLinksBufSize(linksBufsize),
+		scoutfs.WithLinksResolver(c.resolver))
+	for {
+		batch, err := l.Next()
+		if err == syscall.ENOENT || err == syscall.ESTALE {
+			return nil, errGone
+		}
+		if err != nil {
+			return nil, fmt.Errorf("links of inode %v: %v", ino, err)
+		}
+		if batch == nil {
+			break
+		}
+		for _, b := range batch {
+			links = append(links, Link{Parent: b.Parent, Path: b.Path})
+		}
+	}
+
+	if len(links) == 0 {
+		return nil, errGone
+	}
+	return links, nil
+}
+
+// reconcileDir refreshes the cataloged children of a changed directory
+// that are no longer in it
+func (c *Catalog) reconcileDir(ino uint64, f *os.File, stats *UpdateStats) error {
+	kids := c.idx.children[ino]
+	if len(kids) == 0 {
+		return nil
+	}
+
+	dents, err := f.ReadDir(-1)
+	if err != nil {
+		return fmt.Errorf("read dir inode %v: %v", ino, err)
+	}
+	names := make(map[string]struct{}, len(dents))
+	for _, d := range dents {
+		names[d.Name()] = struct{}{}
+	}
+
+	var gone []uint64
+	for kid := range kids {
+		for _, l := range c.idx.entries[kid].Links {
+			if l.Parent != ino {
+				continue
+			}
+			if _, ok := names[path.Base(l.Path)]; !ok {
+				gone = append(gone, kid)
+				break
+			}
+		}
+	}
+	for _, kid := range gone {
+		err = c.refresh(kid, false, stats)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewritePaths updates the paths of the cataloged entries below the
+// directory ino after it was renamed to p
+func (c *Catalog) rewritePaths(ino uint64, p string) error {
+	for _, kid := range c.childInodes(ino) {
+		e := *c.idx.entries[kid]
+		e.Links = append([]Link(nil), e.Links...)
+		for i, l := range e.Links {
+			if l.Parent == ino {
+				e.Links[i].Path = path.Join(p, path.Base(l.Path))
+			}
+		}
+		e.Path = e.Links[0].Path
+		c.idx.put(e)
+		err := c.store.putEntry(e)
+		if err != nil {
+			return err
+		}
+		if e.Mode.IsDir() {
+			err = c.rewritePaths(kid, e.Path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// removeTree removes ino and any entries still cataloged below it.
+// Entries with links outside of the removed tree are kept with their
+// remaining links.
+func (c *Catalog) removeTree(ino uint64) error {
+	for _, kid := range c.childInodes(ino) {
+		e := *c.idx.entries[kid]
+		var links []Link
+		for _, l := range e.Links {
+			if l.Parent != ino {
+				links = append(links, l)
+			}
+		}
+		if len(links) == 0 {
+			err := c.removeTree(kid)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		e.Links = links
+		e.Parent = links[0].Parent
+		e.Path = links[0].Path
+		c.idx.put(e)
+		err := c.store.putEntry(e)
+		if err != nil {
+			return err
+		}
+	}
+	c.idx.remove(ino)
+	return c.store.deleteEntry(ino)
+}
+
+// childInodes returns the cataloged children of ino, copied so that the
+// index can be changed while iterating
+func (c *Catalog) childInodes(ino uint64) []uint64 {
+	kids := make([]uint64, 0, len(c.idx.children[ino]))
+	for kid := range c.idx.children[ino] {
+		kids = append(kids, kid)
+	}
+	return kids
+}
@@ -0,0 +1,353 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package catalog
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type inoSet map[uint64]struct{}
+
+func (s inoSet) add(ino uint64) { s[ino] = struct{}{} }
+
+// index holds the catalog entries with their secondary indices.  The
+// sorted indices are updated along with the entries so queries never
+// have to sort the catalog.
+type index struct {
+	entries  map[uint64]*Entry
+	byPath   map[string]uint64
+	children map[uint64]inoSet
+	byUID    map[uint32]inoSet
+	byGID    map[uint32]inoSet
+	byXattr  map[string]inoSet
+
+	// paths has a key for each link of each entry
+	paths   ordered
+	bySize  ordered
+	byMtime ordered
+}
+
+func newIndex() *index {
+	return &index{
+		entries:  make(map[uint64]*Entry),
+		byPath:   make(map[string]uint64),
+		children: make(map[uint64]inoSet),
+		byUID:    make(map[uint32]inoSet),
+		byGID:    make(map[uint32]inoSet),
+		byXattr:  make(map[string]inoSet),
+	}
+}
+
+func addTo(m map[uint32]inoSet, k uint32, ino uint64) {
+	s, ok := m[k]
+	if !ok {
+		s = make(inoSet)
+		m[k] = s
+	}
+	s.add(ino)
+}
+
+func removeFrom(m map[uint32]inoSet, k uint32, ino uint64) {
+	delete(m[k], ino)
+	if len(m[k]) == 0 {
+		delete(m, k)
+	}
+}
+
+func pathKey(p string, ino uint64) sortKey {
+	return sortKey{s: p, ino: ino}
+}
+
+func sizeKey(e *Entry) sortKey {
+	return sortKey{n: e.Size, ino: e.Ino}
+}
+
+func mtimeKey(e *Entry) sortKey {
+	return sortKey{n: e.Mtime.UnixNano(), ino: e.Ino}
+}
+
+// put adds or replaces the entry for e.Ino
+func (x *index) put(e Entry) {
+	x.remove(e.Ino)
+
+	if len(e.Links) == 0 {
+		e.Links = []Link{{Parent: e.Parent, Path: e.Path}}
+	}
+	ent := &e
+	x.entries[e.Ino] = ent
+	for _, l := range e.Links {
+		x.byPath[l.Path] = e.Ino
+		x.paths.insert(pathKey(l.Path, e.Ino))
+		if e.Ino != l.Parent {
+			s, ok := x.children[l.Parent]
+			if !ok {
+				s = make(inoSet)
+				x.children[l.Parent] = s
+			}
+			s.add(e.Ino)
+		}
+	}
+	addTo(x.byUID, e.UID, e.Ino)
+	addTo(x.byGID, e.GID, e.Ino)
+	for _, name := range e.Xattrs {
+		s, ok := x.byXattr[name]
+		if !ok {
+			s = make(inoSet)
+			x.byXattr[name] = s
+		}
+		s.add(e.Ino)
+	}
+	x.bySize.insert(sizeKey(ent))
+	x.byMtime.insert(mtimeKey(ent))
+}
+
+// remove removes the entry for ino, the entries of its children are kept
+func (x *index) remove(ino uint64) {
+	e, ok := x.entries[ino]
+	if !ok {
+		return
+	}
+
+	delete(x.entries, ino)
+	for _, l := range e.Links {
+		if x.byPath[l.Path] == ino {
+			delete(x.byPath, l.Path)
+		}
+		x.paths.remove(pathKey(l.Path, ino))
+		if s, ok := x.children[l.Parent]; ok {
+			delete(s, ino)
+			if len(s) == 0 {
+				delete(x.children, l.Parent)
+			}
+		}
+	}
+	removeFrom(x.byUID, e.UID, ino)
+	removeFrom(x.byGID, e.GID, ino)
+	for _, name := range e.Xattrs {
+		delete(x.byXattr[name], ino)
+		if len(x.byXattr[name]) == 0 {
+			delete(x.byXattr, name)
+		}
+	}
+	x.bySize.remove(sizeKey(e))
+	x.byMtime.remove(mtimeKey(e))
+}
+
+// all returns a copy of every entry
+func (x *index) all() []Entry {
+	ents := make([]Entry, 0, len(x.entries))
+	for _, e := range x.entries {
+		ents = append(ents, *e)
+	}
+	return ents
+}
+
+// Query selects catalog entries, unset fields match all entries
+type Query struct {
+	// PathPrefix matches the entry with this path and all entries below
+	// it, entries with multiple links match if any link matches
+	PathPrefix string
+	UID        *uint32
+	GID        *uint32
+	// MinSize and MaxSize are inclusive
+	MinSize *int64
+	MaxSize *int64
+	// ModifiedAfter and ModifiedBefore are exclusive
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	// Xattr matches entries with an xattr of this name
+	Xattr string
+	// Limit is the max number of entries returned, 0 for no limit
+	Limit int
+}
+
+func cleanPrefix(p string) string {
+	return strings.Trim(p, "/")
+}
+
+func hasPathPrefix(p, prefix string) bool {
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+func (q Query) match(e *Entry) bool {
+	if p := cleanPrefix(q.PathPrefix); p != "" {
+		found := false
+		for _, l := range e.Links {
+			if hasPathPrefix(l.Path, p) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.UID != nil && e.UID != *q.UID {
+		return false
+	}
+	if q.GID != nil && e.GID != *q.GID {
+		return false
+	}
+	if q.MinSize != nil && e.Size < *q.MinSize {
+		return false
+	}
+	if q.MaxSize != nil && e.Size > *q.MaxSize {
+		return false
+	}
+	if !q.ModifiedAfter.IsZero() && !e.Mtime.After(q.ModifiedAfter) {
+		return false
+	}
+	if !q.ModifiedBefore.IsZero() && !e.Mtime.Before(q.ModifiedBefore) {
+		return false
+	}
+	if q.Xattr != "" {
+		found := false
+		for _, name := range e.Xattrs {
+			if name == q.Xattr {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// keyRange is the keys of a sorted index from lo up to but not
+// including hi
+type keyRange struct {
+	o      *ordered
+	lo, hi sortKey
+}
+
+func (r keyRange) count() int {
+	if !r.lo.less(r.hi) {
+		return 0
+	}
+	return r.o.rank(r.hi) - r.o.rank(r.lo)
+}
+
+func (r keyRange) each(fn func(ino uint64)) {
+	r.o.ascend(r.lo, func(k sortKey) bool {
+		if !k.less(r.hi) {
+			return false
+		}
+		fn(k.ino)
+		return true
+	})
+}
+
+// keyAfter returns the first key after all the keys with n
+func keyAfter(n int64) sortKey {
+	if n == math.MaxInt64 {
+		return sortKey{n: n, s: "\xff", ino: math.MaxUint64}
+	}
+	return sortKey{n: n + 1}
+}
+
+// candidates calls fn with each inode that can match the query, using
+// the secondary index that selects the fewest entries.  Inodes may be
+// passed more than once.
+func (x *index) candidates(q Query, fn func(ino uint64)) {
+	var ranges []keyRange
+	if p := cleanPrefix(q.PathPrefix); p != "" {
+		// "/" sorts just before "0", so the range holds p and p/...
+		// along with siblings like p.old that match filters out
+		ranges = append(ranges, keyRange{o: &x.paths,
+			lo: pathKey(p, 0), hi: pathKey(p+"0", 0)})
+	}
+	if q.MinSize != nil || q.MaxSize != nil {
+		r := keyRange{o: &x.bySize,
+			lo: sortKey{n: math.MinInt64}, hi: keyAfter(math.MaxInt64)}
+		if q.MinSize != nil {
+			r.lo = sortKey{n: *q.MinSize}
+		}
+		if q.MaxSize != nil {
+			r.hi = keyAfter(*q.MaxSize)
+		}
+		ranges = append(ranges, r)
+	}
+	if !q.ModifiedAfter.IsZero() || !q.ModifiedBefore.IsZero() {
+		r := keyRange{o: &x.byMtime,
+			lo: sortKey{n: math.MinInt64}, hi: keyAfter(math.MaxInt64)}
+		if !q.ModifiedAfter.IsZero() {
+			r.lo = keyAfter(q.ModifiedAfter.UnixNano())
+		}
+		if !q.ModifiedBefore.IsZero() {
+			r.hi = sortKey{n: q.ModifiedBefore.UnixNano()}
+		}
+		ranges = append(ranges, r)
+	}
+
+	var best inoSet
+	bestCount := len(x.entries)
+	consider := func(s inoSet) {
+		if best == nil || len(s) < len(best) {
+			best = s
+		}
+	}
+	if q.Xattr != "" {
+		consider(x.byXattr[q.Xattr])
+	}
+	if q.UID != nil {
+		consider(x.byUID[*q.UID])
+	}
+	if q.GID != nil {
+		consider(x.byGID[*q.GID])
+	}
+
+	bestRange := -1
+	if best != nil {
+		bestCount = len(best)
+	}
+	for i, r := range ranges {
+		if n := r.count(); n < bestCount {
+			bestCount = n
+			bestRange = i
+		}
+	}
+
+	switch {
+	case bestRange >= 0:
+		ranges[bestRange].each(fn)
+	case best != nil:
+		for ino := range best {
+			fn(ino)
+		}
+	default:
+		for ino := range x.entries {
+			fn(ino)
+		}
+	}
+}
+
+// find returns the matching entries sorted by path
+func (x *index) find(q Query) []Entry {
+	var ents []Entry
+	seen := make(inoSet)
+	x.candidates(q, func(ino uint64) {
+		if _, ok := seen[ino]; ok {
+			return
+		}
+		seen.add(ino)
+		if e := x.entries[ino]; q.match(e) {
+			ents = append(ents, *e)
+		}
+	})
+	sort.Slice(ents, func(i, j int) bool {
+		return ents[i].Path < ents[j].Path
+	})
+	if q.Limit > 0 && len(ents) > q.Limit {
+		ents = ents[:q.Limit]
+	}
+	return ents
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package catalog
+
+import (
+	"sort"
+)
+
+// orderedChunk is the target number of keys in each chunk of an ordered
+// set, chunks are split when they reach twice this size
+const orderedChunk = 512
+
+// sortKey orders entries in a secondary index by n, then s, then inode
+type sortKey struct {
+	n   int64
+	s   string
+	ino uint64
+}
+
+func (a sortKey) less(b sortKey) bool {
+	if a.n != b.n {
+		return a.n < b.n
+	}
+	if a.s != b.s {
+		return a.s < b.s
+	}
+	return a.ino < b.ino
+}
+
+// ordered is a sorted set of keys stored in bounded chunks so that
+// inserts and removes only move the keys of one chunk
+type ordered struct {
+	chunks [][]sortKey
+	len    int
+}
+
+// find returns the position of the first key >= k, i is len(chunks) if
+// all keys are less than k
+func (o *ordered) find(k sortKey) (int, int) {
+	i := sort.Search(len(o.chunks), func(i int) bool {
+		c := o.chunks[i]
+		return !c[len(c)-1].less(k)
+	})
+	if i == len(o.chunks) {
+		return i, 0
+	}
+	c := o.chunks[i]
+	j := sort.Search(len(c), func(j int) bool {
+		return !c[j].less(k)
+	})
+	return i, j
+}
+
+func (o *ordered) insert(k sortKey) {
+	if len(o.chunks) == 0 {
+		o.chunks = append(o.chunks, []sortKey{k})
+		o.len++
+		return
+	}
+
+	i, j := o.find(k)
+	if i == len(o.chunks) {
+		i = len(o.chunks) - 1
+		j = len(o.chunks[i])
+	} else if o.chunks[i][j] == k {
+		return
+	}
+
+	c := append(o.chunks[i], sortKey{})
+	copy(c[j+1:], c[j:])
+	c[j] = k
+	o.chunks[i] = c
+	o.len++
+
+	if len(c) >= 2*orderedChunk {
+		right := append([]sortKey(nil), c[orderedChunk:]...)
+		o.chunks[i] = c[:orderedChunk:orderedChunk]
+		o.chunks = append(o.chunks, nil)
+		copy(o.chunks[i+2:], o.chunks[i+1:])
+		o.chunks[i+1] = right
+	}
+}
+
+func (o *ordered) remove(k sortKey) {
+	i, j := o.find(k)
+	if i == len(o.chunks) || o.chunks[i][j] != k {
+		return
+	}
+
+	c := o.chunks[i]
+	copy(c[j:], c[j+1:])
+	c[len(c)-1] = sortKey{}
+	o.chunks[i] = c[:len(c)-1]
+	o.len--
+
+	if len(o.chunks[i]) == 0 {
+		copy(o.chunks[i:], o.chunks[i+1:])
+		o.chunks[len(o.chunks)-1] = nil
+		o.chunks = o.chunks[:len(o.chunks)-1]
+	}
+}
+
+// rank returns the number of keys less than k
+func (o *ordered) rank(k sortKey) int {
+	i, j := o.find(k)
+	n := j
+	for _, c := range o.chunks[:i] {
+		n += len(c)
+	}
+	return n
+}
+
+// ascend calls fn for each key >= from in order until fn returns false
+func (o *ordered) ascend(from sortKey, fn func(sortKey) bool) {
+	i, j := o.find(from)
+	for ; i < len(o.chunks); i++ {
+		for _, k := range o.chunks[i][j:] {
+			if !fn(k) {
+				return
+			}
+		}
+		j = 0
+	}
+}
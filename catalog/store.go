@@ -0,0 +1,272 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package catalog
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	scoutfs "github.com/versity/scoutfs-go"
+)
+
+const (
+	snapshotFile  = "catalog.snap"
+	journalPrefix = "catalog.journal."
+	formatVersion = 2
+)
+
+// snapshot is the full catalog state written on compaction
+type snapshot struct {
+	Version int
+	Cursor  scoutfs.InodesEntry
+	// Journal is the first journal segment not included in the snapshot
+	Journal uint64
+	Entries []Entry
+}
+
+// record is a single change appended to the journal
+type record struct {
+	Put    *Entry
+	Delete uint64
+	Cursor *scoutfs.InodesEntry
+}
+
+// store persists the catalog as a snapshot and a journal of changes made
+// since the snapshot was written.  The journal is a sequence of segments,
+// each open of the store appends a new segment so that the existing
+// journal doesn't have to be rewritten.
+type store struct {
+	dir     string
+	journal *os.File
+	w       *bufio.Writer
+	enc     *gob.Encoder
+	// seq is the segment number of the open journal segment, or of the
+	// next segment before the journal is opened
+	seq uint64
+	// records is the number of records in all the journal segments
+	records int
+	// appended is the number of records in the open segment
+	appended int
+}
+
+func (s *store) segmentPath(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%v%016x", journalPrefix, seq))
+}
+
+// segments returns the journal segment numbers in order
+func (s *store) segments() ([]uint64, error) {
+	names, err := filepath.Glob(filepath.Join(s.dir, journalPrefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var seqs []uint64
+	for _, name := range names {
+		seq, err := strconv.ParseUint(
+			strings.TrimPrefix(filepath.Base(name), journalPrefix), 16, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs, nil
+}
+
+// load reads the snapshot and replays the journal, calling put and
+// remove for each journaled change.  A partially written record at the
+// end of a journal segment is ignored.
+func (s *store) load(put func(Entry), remove func(uint64)) (scoutfs.InodesEntry, error) {
+	var cursor scoutfs.InodesEntry
+	var first uint64
+
+	f, err := os.Open(filepath.Join(s.dir, snapshotFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return cursor, err
+	}
+	if err == nil {
+		var snap snapshot
+		err = gob.NewDecoder(bufio.NewReader(f)).Decode(&snap)
+		f.Close()
+		if err != nil {
+			return cursor, fmt.Errorf("decode snapshot: %v", err)
+		}
+		if snap.Version != formatVersion {
+			return cursor, fmt.Errorf("unsupported snapshot version %v", snap.Version)
+		}
+		for _, e := range snap.Entries {
+			put(e)
+		}
+		cursor = snap.Cursor
+		first = snap.Journal
+	}
+
+	seqs, err := s.segments()
+	if err != nil {
+		return cursor, err
+	}
+	s.seq = first
+	s.records = 0
+	for _, seq := range seqs {
+		if seq < first {
+			// left behind by an interrupted compaction
+			os.Remove(s.segmentPath(seq))
+			continue
+		}
+		cursor, err = s.replay(seq, cursor, put, remove)
+		if err != nil {
+			return cursor, err
+		}
+		s.seq = seq + 1
+	}
+
+	return cursor, nil
+}
+
+func (s *store) replay(seq uint64, cursor scoutfs.InodesEntry, put func(Entry), remove func(uint64)) (scoutfs.InodesEntry, error) {
+	f, err := os.Open(s.segmentPath(seq))
+	if err != nil {
+		return cursor, err
+	}
+	defer f.Close()
+
+	dec := gob.NewDecoder(bufio.NewReader(f))
+	for {
+		var r record
+		err := dec.Decode(&r)
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			return cursor, nil
+		}
+		if err != nil {
+			return cursor, fmt.Errorf("decode journal: %v", err)
+		}
+
+		s.records++
+		switch {
+		case r.Put != nil:
+			put(*r.Put)
+		case r.Cursor != nil:
+			cursor = *r.Cursor
+		default:
+			remove(r.Delete)
+		}
+	}
+}
+
+// open starts a new journal segment for appending records
+func (s *store) open() error {
+	f, err := os.OpenFile(s.segmentPath(s.seq),
+		os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	s.journal = f
+	s.w = bufio.NewWriter(f)
+	s.enc = gob.NewEncoder(s.w)
+	s.appended = 0
+	return nil
+}
+
+// compact writes a new snapshot of entries and starts an empty journal
+func (s *store) compact(cursor scoutfs.InodesEntry, entries []Entry) error {
+	if s.journal != nil {
+		s.journal.Close()
+		s.journal = nil
+	}
+	s.seq++
+
+	tmp, err := os.CreateTemp(s.dir, snapshotFile+".tmp")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	err = gob.NewEncoder(w).Encode(snapshot{
+		Version: formatVersion,
+		Cursor:  cursor,
+		Journal: s.seq,
+		Entries: entries,
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	tmp.Close()
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(s.dir, snapshotFile))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write snapshot: %v", err)
+	}
+
+	seqs, err := s.segments()
+	if err != nil {
+		return err
+	}
+	for _, seq := range seqs {
+		if seq < s.seq {
+			os.Remove(s.segmentPath(seq))
+		}
+	}
+
+	s.records = 0
+	return s.open()
+}
+
+func (s *store) append(r record) error {
+	s.records++
+	s.appended++
+	return s.enc.Encode(r)
+}
+
+func (s *store) putEntry(e Entry) error {
+	return s.append(record{Put: &e})
+}
+
+func (s *store) deleteEntry(ino uint64) error {
+	return s.append(record{Delete: ino})
+}
+
+func (s *store) setCursor(c scoutfs.InodesEntry) error {
+	return s.append(record{Cursor: &c})
+}
+
+// sync flushes journaled records to stable storage
+func (s *store) sync() error {
+	err := s.w.Flush()
+	if err != nil {
+		return err
+	}
+	return s.journal.Sync()
+}
+
+// close flushes and closes the journal, an empty segment is removed
+func (s *store) close() error {
+	if s.journal == nil {
+		return nil
+	}
+	err := s.w.Flush()
+	cerr := s.journal.Close()
+	s.journal = nil
+	if err == nil && cerr == nil && s.appended == 0 {
+		os.Remove(s.segmentPath(s.seq))
+	}
+	if err != nil {
+		return err
+	}
+	return cerr
+}
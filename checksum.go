@@ -0,0 +1,404 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc64"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const (
+	// ChecksumXattr is the hidden xattr used to store a chunk manifest
+	// with a file when it is small enough to fit in an xattr value
+	ChecksumXattr = "scoutfs.hide.chunksums"
+	// xattrValueMax is the largest xattr value supported
+	xattrValueMax = 64 * 1024
+	// defaultChunkSize is the default size of a checksummed chunk
+	defaultChunkSize = 1024 * 1024
+	// dataWaitOpAll matches all data waiter operations
+	dataWaitOpAll = DATAWAITOPREAD | DATAWAITOPWRITE | DATAWAITOPCHANGESIZE
+)
+
+// ErrChecksumMismatch is returned when data does not match its manifest
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ChecksumError describes the chunk that failed verification
+type ChecksumError struct {
+	Offset uint64
+	Length uint64
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("%v at offset %v length %v",
+		ErrChecksumMismatch, e.Offset, e.Length)
+}
+
+// Is matches ErrChecksumMismatch
+func (e *ChecksumError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
+// ChecksumAlgorithm is a named hash used for chunk manifests
+type ChecksumAlgorithm struct {
+	Name string
+	New  func() hash.Hash
+}
+
+var (
+	// ChecksumSHA256 is the SHA-256 hash
+	ChecksumSHA256 = ChecksumAlgorithm{Name: "sha256", New: sha256.New}
+	// ChecksumCRC64 is the CRC-64 hash with ECMA polynomial
+	ChecksumCRC64 = ChecksumAlgorithm{Name: "crc64", New: func() hash.Hash {
+		return crc64.New(crc64.MakeTable(crc64.ECMA))
+	}}
+)
+
+var (
+	checksumMu         sync.RWMutex
+	checksumAlgorithms = map[string]ChecksumAlgorithm{
+		ChecksumSHA256.Name: ChecksumSHA256,
+		ChecksumCRC64.Name:  ChecksumCRC64,
+	}
+)
+
+// RegisterChecksum makes a checksum algorithm available for verifying
+// manifests by name, such as an xxhash implementation
+func RegisterChecksum(alg ChecksumAlgorithm) {
+	checksumMu.Lock()
+	checksumAlgorithms[alg.Name] = alg
+	checksumMu.Unlock()
+}
+
+func lookupChecksum(name string) (ChecksumAlgorithm, error) {
+	checksumMu.RLock()
+	defer checksumMu.RUnlock()
+	alg, ok := checksumAlgorithms[name]
+	if !ok {
+		return ChecksumAlgorithm{}, fmt.Errorf("unknown checksum algorithm %q", name)
+	}
+	return alg, nil
+}
+
+// ChunkManifest holds the checksums of each fixed size chunk of a file
+type ChunkManifest struct {
+	Algorithm string
+	ChunkSize uint64
+	Size      uint64
+	Sums      [][]byte
+}
+
+// ChunkHasher computes a ChunkManifest for data written to it
+type ChunkHasher struct {
+	m    ChunkManifest
+	h    hash.Hash
+	fill uint64
+}
+
+// NewChunkHasher creates a ChunkHasher for the algorithm and chunk size.
+// The chunk size must be a multiple of 4KB.
+func NewChunkHasher(alg ChecksumAlgorithm, chunkSize uint64) (*ChunkHasher, error) {
+	if chunkSize == 0 || chunkSize%scoutfsBS != 0 {
+		return nil, fmt.Errorf("chunk size %v not a multiple of %v",
+			chunkSize, scoutfsBS)
+	}
+	return &ChunkHasher{
+		m: ChunkManifest{
+			Algorithm: alg.Name,
+			ChunkSize: chunkSize,
+		},
+		h: alg.New(),
+	}, nil
+}
+
+// Write adds data to the manifest
+func (c *ChunkHasher) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		l := c.m.ChunkSize - c.fill
+		if uint64(len(b)) < l {
+			l = uint64(len(b))
+		}
+		c.h.Write(b[:l])
+		c.fill += l
+		c.m.Size += l
+		b = b[l:]
+		if c.fill == c.m.ChunkSize {
+			c.m.Sums = append(c.m.Sums, c.h.Sum(nil))
+			c.h.Reset()
+			c.fill = 0
+		}
+	}
+	return n, nil
+}
+
+// Manifest returns the manifest for all data written
+func (c *ChunkHasher) Manifest() *ChunkManifest {
+	m := c.m
+	m.Sums = append([][]byte(nil), c.m.Sums...)
+	if c.fill > 0 {
+		m.Sums = append(m.Sums, c.h.Sum(nil))
+	}
+	return &m
+}
+
+// MarshalText encodes the manifest as a header line followed by one hex
+// checksum per line
+func (m *ChunkManifest) MarshalText() ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%v %v %v\n", m.Algorithm, m.ChunkSize, m.Size)
+	for _, sum := range m.Sums {
+		b.WriteString(hex.EncodeToString(sum))
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+// UnmarshalText decodes a manifest encoded with MarshalText
+func (m *ChunkManifest) UnmarshalText(text []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+	if !scanner.Scan() {
+		return fmt.Errorf("missing manifest header")
+	}
+	fields := strings.Fields(scanner.Text())
+	if len(fields) != 3 {
+		return fmt.Errorf("parse manifest header: %q", scanner.Text())
+	}
+
+	chunkSize, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil || chunkSize == 0 {
+		return fmt.Errorf("parse chunk size %q: %v", fields[1], err)
+	}
+	size, err := strconv.ParseUint(fields[2], 10, 64)
+	if err != nil {
+		return fmt.Errorf("parse size %q: %v", fields[2], err)
+	}
+
+	var sums [][]byte
+	for scanner.Scan() {
+		sum, err := hex.DecodeString(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("parse checksum %q: %v", scanner.Text(), err)
+		}
+		sums = append(sums, sum)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("parse manifest: %v", err)
+	}
+
+	if uint64(len(sums)) != divRoundUp(size, chunkSize)/chunkSize {
+		return fmt.Errorf("manifest has %v checksums for size %v", len(sums), size)
+	}
+
+	m.Algorithm = fields[0]
+	m.ChunkSize = chunkSize
+	m.Size = size
+	m.Sums = sums
+	return nil
+}
+
+// checkRange returns an error if the range isn't chunk aligned, ranges
+// may end with the final partial chunk of the file
+func (m *ChunkManifest) checkRange(offset, length uint64) error {
+	if offset%m.ChunkSize != 0 {
+		return fmt.Errorf("offset %v not aligned to chunk size %v",
+			offset, m.ChunkSize)
+	}
+	if length%m.ChunkSize != 0 && offset+length < m.Size {
+		return fmt.Errorf("length %v not aligned to chunk size %v",
+			length, m.ChunkSize)
+	}
+	return nil
+}
+
+// Verify checks data read at offset against the manifest.  Offset must be
+// chunk aligned and b must cover whole chunks, except for the final chunk
+// of the file.
+func (m *ChunkManifest) Verify(offset uint64, b []byte) error {
+	alg, err := lookupChecksum(m.Algorithm)
+	if err != nil {
+		return err
+	}
+	if offset%m.ChunkSize != 0 {
+		return fmt.Errorf("offset %v not aligned to chunk size %v",
+			offset, m.ChunkSize)
+	}
+
+	h := alg.New()
+	for len(b) > 0 {
+		i := offset / m.ChunkSize
+		if i >= uint64(len(m.Sums)) {
+			return &ChecksumError{Offset: offset, Length: uint64(len(b))}
+		}
+
+		l := m.ChunkSize
+		if offset+l > m.Size {
+			l = m.Size - offset
+		}
+		if uint64(len(b)) < l {
+			return fmt.Errorf("partial chunk at offset %v: %v < %v: %w",
+				offset, len(b), l, ErrChecksumMismatch)
+		}
+
+		h.Reset()
+		h.Write(b[:l])
+		if !bytes.Equal(h.Sum(nil), m.Sums[i]) {
+			return &ChecksumError{Offset: offset, Length: l}
+		}
+
+		offset += l
+		b = b[l:]
+	}
+
+	return nil
+}
+
+// GetChunkManifest reads the chunk manifest stored with file handle
+func GetChunkManifest(f *os.File) (*ChunkManifest, error) {
+	b := make([]byte, xattrValueMax)
+	n, err := fgetxattr(f, ChecksumXattr, b)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &ChunkManifest{}
+	err = m.UnmarshalText(b[:n])
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SetChunkManifest stores the chunk manifest with file handle.  Manifests
+// that do not fit within an xattr value must be stored in a sidecar.
+func SetChunkManifest(f *os.File, m *ChunkManifest) error {
+	b, err := m.MarshalText()
+	if err != nil {
+		return err
+	}
+	if len(b) >= xattrValueMax {
+		return fmt.Errorf("manifest size %v exceeds xattr limit %v",
+			len(b), xattrValueMax-1)
+	}
+	return fsetxattr(f, ChecksumXattr, b, 0)
+}
+
+// ReadChunkManifest reads a manifest from a sidecar file
+func ReadChunkManifest(path string) (*ChunkManifest, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &ChunkManifest{}
+	err = m.UnmarshalText(b)
+	if err != nil {
+		return nil, fmt.Errorf("parse %q: %v", path, err)
+	}
+	return m, nil
+}
+
+// WriteChunkManifest writes a manifest to a sidecar file
+func WriteChunkManifest(path string, m *ChunkManifest) error {
+	b, err := m.MarshalText()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// FStageFileVerified verifies b against the manifest before staging it
+// to file handle.  If verification fails the data waiters for the range
+// are sent EIO and the returned error matches ErrChecksumMismatch.
+// An open file within scoutfs is supplied for ioctls
+// (usually just the base mount point directory)
+func FStageFileVerified(dirfd, f *os.File, m *ChunkManifest, version, offset uint64, b []byte) (int, error) {
+	err := m.checkRange(offset, uint64(len(b)))
+	if err != nil {
+		return 0, err
+	}
+
+	err = m.Verify(offset, b)
+	if err != nil {
+		return 0, failDataWaiters(dirfd, f, version, offset, uint64(len(b)), err)
+	}
+
+	return FStageFile(f, version, offset, b)
+}
+
+// StageMoveAtVerified verifies the contents of "from" against the
+// manifest before moving the extents with StageMoveAt.  The manifest
+// describes the "to" file, so toOffset must be chunk aligned and len must
+// cover whole chunks, except for the final chunk of the file.  If
+// verification fails the data waiters for the range are sent EIO and the
+// returned error matches ErrChecksumMismatch.
+// An open file within scoutfs is supplied for ioctls
+// (usually just the base mount point directory)
+func StageMoveAtVerified(dirfd, from, to *os.File, m *ChunkManifest, len, fromOffset, toOffset, version uint64) error {
+	err := m.checkRange(toOffset, len)
+	if err != nil {
+		return err
+	}
+
+	check := len
+	if toOffset >= m.Size {
+		check = 0
+	} else if toOffset+check > m.Size {
+		check = m.Size - toOffset
+	}
+
+	buf := make([]byte, m.ChunkSize)
+	for done := uint64(0); done < check; {
+		l := m.ChunkSize
+		if check-done < l {
+			l = check - done
+		}
+		n, err := from.ReadAt(buf[:l], int64(fromOffset+done))
+		if uint64(n) < l {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return fmt.Errorf("read from offset %v: %v", fromOffset+done, err)
+		}
+		err = m.Verify(toOffset+done, buf[:n])
+		if err != nil {
+			return failDataWaiters(dirfd, to, version, toOffset, len, err)
+		}
+		done += l
+	}
+
+	return StageMoveAt(from, to, len, fromOffset, toOffset, version)
+}
+
+func failDataWaiters(dirfd, f *os.File, version, offset, count uint64, verr error) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return verr
+	}
+	ino, err := fileIno(fi)
+	if err != nil {
+		return verr
+	}
+
+	err = SendDataWaitErr(dirfd, ino, version, offset, dataWaitOpAll, count,
+		-int64(syscall.EIO))
+	if err != nil {
+		return fmt.Errorf("%w (data wait err: %v)", verr, err)
+	}
+	return verr
+}
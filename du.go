@@ -0,0 +1,233 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// DirUsage is the usage of a directory and everything below it.  Sizes
+// are logical file sizes and the bytes of online and offline data, not
+// the allocated blocks reported by du.
+type DirUsage struct {
+	Path string `json:"path"`
+	// Depth is the number of directories below the walk root
+	Depth        int    `json:"depth"`
+	Size         uint64 `json:"size"`
+	OnlineBytes  uint64 `json:"online_bytes"`
+	OfflineBytes uint64 `json:"offline_bytes"`
+	// Files is the number of regular files
+	Files uint64 `json:"files"`
+	// Inodes is the number of inodes of all types, including the
+	// directory itself
+	Inodes uint64 `json:"inodes"`
+}
+
+// DUSortKey is the DirUsage field a DiskUsageReport is sorted by
+type DUSortKey string
+
+const (
+	DUSortPath    DUSortKey = "path"
+	DUSortSize    DUSortKey = "size"
+	DUSortOnline  DUSortKey = "online"
+	DUSortOffline DUSortKey = "offline"
+	DUSortFiles   DUSortKey = "files"
+	DUSortInodes  DUSortKey = "inodes"
+)
+
+// DiskUsageReport is the usage of each directory in a tree
+type DiskUsageReport []DirUsage
+
+// Sort sorts the report by key, largest first for all keys but path
+func (r DiskUsageReport) Sort(key DUSortKey) error {
+	var val func(u DirUsage) uint64
+	switch key {
+	case DUSortPath:
+		sort.Slice(r, func(i, j int) bool { return r[i].Path < r[j].Path })
+		return nil
+	case DUSortSize:
+		val = func(u DirUsage) uint64 { return u.Size }
+	case DUSortOnline:
+		val = func(u DirUsage) uint64 { return u.OnlineBytes }
+	case DUSortOffline:
+		val = func(u DirUsage) uint64 { return u.OfflineBytes }
+	case DUSortFiles:
+		val = func(u DirUsage) uint64 { return u.Files }
+	case DUSortInodes:
+		val = func(u DirUsage) uint64 { return u.Inodes }
+	default:
+		return fmt.Errorf("unknown sort key %q", key)
+	}
+
+	sort.Slice(r, func(i, j int) bool {
+		vi, vj := val(r[i]), val(r[j])
+		if vi != vj {
+			return vi > vj
+		}
+		return r[i].Path < r[j].Path
+	})
+	return nil
+}
+
+// String returns the report as a table with human readable sizes
+func (r DiskUsageReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%8v %8v %8v %10v %10v  %v\n",
+		"SIZE", "ONLINE", "OFFLINE", "FILES", "INODES", "PATH")
+	for _, u := range r {
+		p := u.Path
+		if p == "" {
+			p = "."
+		}
+		fmt.Fprintf(&b, "%8v %8v %8v %10v %10v  %v\n",
+			byteToHuman(u.Size), byteToHuman(u.OnlineBytes),
+			byteToHuman(u.OfflineBytes), u.Files, u.Inodes, p)
+	}
+	return b.String()
+}
+
+// DiskUsageTree aggregates usage per directory using a Walker.  The
+// directory tree is built once and reused by later calls until Refresh.
+type DiskUsageTree struct {
+	mu     sync.Mutex
+	walker *Walker
+	tree   *WalkTree
+}
+
+// NewDiskUsageTree creates a DiskUsageTree for the filesystem of dirfd,
+// options are passed to NewWalker
+// An open file within scoutfs is supplied for ioctls
+// (usually just the base mount point directory)
+func NewDiskUsageTree(dirfd *os.File, opts ...WKOption) *DiskUsageTree {
+	return &DiskUsageTree{walker: NewWalker(dirfd, opts...)}
+}
+
+// Refresh rebuilds the cached directory tree
+func (d *DiskUsageTree) Refresh() error {
+	t, err := d.walker.Tree()
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.tree = t
+	d.mu.Unlock()
+	return nil
+}
+
+// Usage returns the usage of root, a path relative to the filesystem
+// root, and of each directory below it.  Inodes with multiple links are
+// counted once, in the directory of whichever of their paths below root
+// sorts first, so that the totals don't depend on the walk order.
+func (d *DiskUsageTree) Usage(root string) (DiskUsageReport, error) {
+	d.mu.Lock()
+	t := d.tree
+	d.mu.Unlock()
+	if t == nil {
+		err := d.Refresh()
+		if err != nil {
+			return nil, err
+		}
+		d.mu.Lock()
+		t = d.tree
+		d.mu.Unlock()
+	}
+
+	root = strings.Trim(root, "/")
+	dirs := make(map[string]*DirUsage)
+	// first path of each inode with multiple links
+	linked := make(map[uint64]WalkEntry)
+
+	dirUsage := func(p string) *DirUsage {
+		u, ok := dirs[p]
+		if !ok {
+			depth := 0
+			if p != root {
+				depth = strings.Count(strings.TrimPrefix(p, root), "/")
+				if root == "" {
+					depth++
+				}
+			}
+			u = &DirUsage{Path: p, Depth: depth}
+			dirs[p] = u
+		}
+		return u
+	}
+
+	add := func(e WalkEntry) {
+		isDir := e.Info.IsDir()
+		var size uint64
+		var files uint64
+		if e.Info.Mode().IsRegular() {
+			size = uint64(e.Info.Size())
+			files = 1
+		}
+
+		dir := e.Path
+		if !isDir && e.Path != root {
+			dir = parentPath(e.Path)
+		}
+		for {
+			u := dirUsage(dir)
+			u.Size += size
+			u.OnlineBytes += e.Stat.Online_blocks * scoutfsBS
+			u.OfflineBytes += e.Stat.Offline_blocks * scoutfsBS
+			u.Files += files
+			u.Inodes++
+			if dir == root || dir == "" {
+				break
+			}
+			dir = parentPath(dir)
+		}
+	}
+
+	err := d.walker.WalkTree(t, root, func(e WalkEntry) error {
+		isDir := e.Info.IsDir()
+		if isDir {
+			dirUsage(e.Path)
+		}
+
+		if st, ok := e.Info.Sys().(*syscall.Stat_t); ok && !isDir && st.Nlink > 1 {
+			if l, ok := linked[e.Ino]; !ok || e.Path < l.Path {
+				linked[e.Ino] = e
+			}
+			return nil
+		}
+
+		add(e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range linked {
+		add(e)
+	}
+
+	report := make(DiskUsageReport, 0, len(dirs))
+	for _, u := range dirs {
+		report = append(report, *u)
+	}
+	report.Sort(DUSortPath)
+	return report, nil
+}
+
+// parentPath returns the parent of a path relative to the filesystem
+// root, the root is ""
+func parentPath(p string) string {
+	dir := path.Dir(p)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+// This example is a minimal HSM copytool using a local directory as
+// the archive.
+//
+// ./copytool /mnt/scoutfs /archive archive /mnt/scoutfs/file
+// ./copytool /mnt/scoutfs /archive release /mnt/scoutfs/file
+// ./copytool /mnt/scoutfs /archive restore /mnt/scoutfs/file
+// ./copytool /mnt/scoutfs /archive serve
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	scoutfs "github.com/versity/scoutfs-go"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage:", os.Args[0],
+		"<scoutfs mount point> <archive dir> archive|release|restore <file>")
+	fmt.Fprintln(os.Stderr, "      ", os.Args[0],
+		"<scoutfs mount point> <archive dir> serve")
+	os.Exit(1)
+}
+
+func main() {
+	if len(os.Args) < 4 || os.Args[1] == "-h" {
+		usage()
+	}
+
+	mnt, err := os.Open(os.Args[1])
+	if err != nil {
+		log.Fatalln("error open mount:", err)
+	}
+	defer mnt.Close()
+
+	a, err := scoutfs.NewLocalArchive(mnt, os.Args[2],
+		scoutfs.WithRestoreErrorFunc(func(ino uint64, err error) {
+			log.Printf("restore inode %v: %v", ino, err)
+		}))
+	if err != nil {
+		log.Fatalln("error archive:", err)
+	}
+
+	cmd := os.Args[3]
+	if cmd == "serve" {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		err = a.ServeWaiters(ctx)
+		if err != nil && err != context.Canceled {
+			log.Fatalln("error serve waiters:", err)
+		}
+		return
+	}
+
+	if len(os.Args) != 5 {
+		usage()
+	}
+
+	f, err := os.OpenFile(os.Args[4], os.O_RDWR, 0)
+	if err != nil {
+		log.Fatalln("error open file:", err)
+	}
+	defer f.Close()
+
+	switch cmd {
+	case "archive":
+		info, err := a.Archive(f)
+		if err != nil {
+			log.Fatalln("error archive:", err)
+		}
+		fmt.Println(info)
+	case "release":
+		err = a.Release(f)
+		if err != nil {
+			log.Fatalln("error release:", err)
+		}
+	case "restore":
+		err = a.Restore(f)
+		if err != nil {
+			log.Fatalln("error restore:", err)
+		}
+	default:
+		usage()
+	}
+}
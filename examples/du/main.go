@@ -0,0 +1,78 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	scoutfs "github.com/versity/scoutfs-go"
+)
+
+func main() {
+	mountPath := flag.String("mount", "", "mount path name")
+	sortKey := flag.String("sort", "path", "sort by path, size, online, offline, files or inodes")
+	depth := flag.Int("depth", -1, "max directory depth to show, -1 for all")
+	top := flag.Int("n", 0, "show only the first n directories, 0 for all")
+	asJSON := flag.Bool("json", false, "output json")
+	workers := flag.Int("workers", 8, "parallel walk workers")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage:", os.Args[0], "-mount <scoutfs mount point> [flags] [subdir]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *mountPath == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	f, err := os.Open(*mountPath)
+	if err != nil {
+		log.Fatalf("open %q: %v", *mountPath, err)
+	}
+	defer f.Close()
+
+	dut := scoutfs.NewDiskUsageTree(f, scoutfs.WithWalkerWorkers(*workers))
+	report, err := dut.Usage(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("disk usage: %v", err)
+	}
+
+	if *depth >= 0 {
+		var filtered scoutfs.DiskUsageReport
+		for _, u := range report {
+			if u.Depth <= *depth {
+				filtered = append(filtered, u)
+			}
+		}
+		report = filtered
+	}
+
+	err = report.Sort(scoutfs.DUSortKey(*sortKey))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *top > 0 && len(report) > *top {
+		report = report[:*top]
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+		if err != nil {
+			log.Fatalf("encode json: %v", err)
+		}
+		return
+	}
+
+	fmt.Print(report)
+}
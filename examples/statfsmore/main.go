@@ -41,4 +41,16 @@ func main() {
 	}
 
 	fmt.Printf("%+v\n", df)
+	fmt.Printf("meta used %.1f%% data used %.1f%%\n",
+		df.MetaUsedPercent(), df.DataUsedPercent())
+
+	entries, err := scoutfs.AllocDetail(f)
+	if err != nil {
+		log.Fatalf("error AllocDetail: %v", err)
+	}
+
+	for _, p := range scoutfs.AllocPools(entries) {
+		fmt.Printf("%-9v %016x meta avail %v freed %v data avail %v freed %v\n",
+			p.Owner, p.ID, p.MetaAvail, p.MetaFreed, p.DataAvail, p.DataFreed)
+	}
 }
@@ -0,0 +1,87 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// xattrValueBufsize is the initial value buffer size when none is given
+const xattrValueBufsize = 4096
+
+// GetXattr returns the value of the xattr name, including hidden xattrs.
+// If passed in buffer is nil or too small, call will allocate its own
+// buffer.  The returned value is only valid until the buffer is reused.
+func GetXattr(f *os.File, name string, b []byte) ([]byte, error) {
+	if len(b) == 0 {
+		b = make([]byte, xattrValueBufsize)
+	}
+
+	for {
+		n, err := fgetxattr(f, name, b)
+		if err == nil {
+			return b[:n], nil
+		}
+		if err != syscall.ERANGE {
+			return nil, err
+		}
+
+		// probe for the current size, the value can change between calls
+		n, err = fgetxattr(f, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		if n <= len(b) {
+			n = len(b) * 2
+		}
+		b = make([]byte, n)
+	}
+}
+
+// GetHiddenXattrs returns the names and values of all xattrs for file
+// handle, including hidden xattrs.  If passed in buffer is nil, call will
+// allocate its own buffer.  The buffer is reused for listing names and
+// reading values, the returned values are copies.
+func GetHiddenXattrs(f *os.File, b []byte) (map[string][]byte, error) {
+	if len(b) == 0 {
+		b = make([]byte, listattrBufsize)
+	}
+
+	var names []string
+	lxr := NewListXattrHidden(f, b)
+	for {
+		n, err := lxr.Next()
+		if err != nil {
+			return nil, fmt.Errorf("list xattrs: %v", err)
+		}
+		if n == nil {
+			break
+		}
+		names = append(names, n...)
+	}
+
+	xattrs := make(map[string][]byte, len(names))
+	for _, name := range names {
+		v, err := GetXattr(f, name, b)
+		if err == syscall.ENODATA {
+			// removed since listing
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get xattr %q: %v", name, err)
+		}
+		if cap(v) > len(b) {
+			// keep the larger buffer for later values
+			b = v[:cap(v)]
+		}
+		xattrs[name] = append([]byte(nil), v...)
+	}
+
+	return xattrs, nil
+}
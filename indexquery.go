@@ -0,0 +1,271 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"math"
+	"os"
+)
+
+const (
+	// indexWindows is the number of windows the minor range is initially
+	// split into for reverse searches
+	indexWindows = 1024
+	// indexWindowMax is the largest reverse search window width
+	indexWindowMax = 1 << 63
+)
+
+// IndexReverseSearch returns the entries of an index major from the end
+// minor down to the start minor.  The index can only be read in
+// ascending order, so the range is read in windows from the end.  The
+// window width adapts so that each window holds a few batches of
+// entries.  Entries within a window are held in memory, so many entries
+// with the same minor will all be held at once.
+type IndexReverseSearch struct {
+	search *IndexSearch
+	key    uint8
+	start  uint64
+	// hi is the top minor of the next window
+	hi      uint64
+	width   uint64
+	done    bool
+	pending []IndexEnt
+}
+
+// NewIndexReverseSearch creates a descending search of index major key.
+// Of the options only the batch size applies.
+func NewIndexReverseSearch(f *os.File, key uint8, start, end uint64, opts ...IOption) *IndexReverseSearch {
+	return &IndexReverseSearch{
+		search: NewIndexSearch(f, key, start, end, opts...),
+		key:    key,
+		start:  start,
+		hi:     end,
+		width:  (end-start)/indexWindows + 1,
+		done:   start > end,
+	}
+}
+
+// Next gets the next batch of entries in descending minor and inode
+// order, returns nil when complete
+func (r *IndexReverseSearch) Next() ([]IndexEnt, error) {
+	batch := int(r.search.batch)
+	for len(r.pending) == 0 {
+		if r.done {
+			return nil, nil
+		}
+
+		lo := r.start
+		if r.hi-r.start >= r.width {
+			lo = r.hi - (r.width - 1)
+		}
+
+		ents, err := r.readWindow(lo, r.hi)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case len(ents) < batch && r.width < indexWindowMax:
+			r.width *= 2
+		case len(ents) > 4*batch && r.width > 1:
+			r.width /= 2
+		}
+
+		if lo == r.start {
+			r.done = true
+		} else {
+			r.hi = lo - 1
+		}
+
+		for i, j := 0, len(ents)-1; i < j; i, j = i+1, j-1 {
+			ents[i], ents[j] = ents[j], ents[i]
+		}
+		r.pending = ents
+	}
+
+	n := batch
+	if n > len(r.pending) {
+		n = len(r.pending)
+	}
+	ents := r.pending[:n]
+	r.pending = r.pending[n:]
+	return ents, nil
+}
+
+func (r *IndexReverseSearch) readWindow(lo, hi uint64) ([]IndexEnt, error) {
+	r.search.pos = indexEntry{Major: r.key, Minor: lo}
+	r.search.end = indexEntry{Major: r.key, Minor: hi, Ino: math.MaxUint64}
+
+	var ents []IndexEnt
+	for {
+		e, err := r.search.Next()
+		if err != nil {
+			return nil, err
+		}
+		if e == nil {
+			return ents, nil
+		}
+		ents = append(ents, e...)
+		if e[len(e)-1].Value == hi && e[len(e)-1].Inode == math.MaxUint64 {
+			// position would wrap into the next minor
+			return ents, nil
+		}
+	}
+}
+
+// IndexTopN returns up to n entries of index major key with the largest
+// minors between start and end, in descending order
+func IndexTopN(f *os.File, key uint8, start, end uint64, n int) ([]IndexEnt, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	batch := uint64(indexXattrBatch)
+	if uint64(n) < batch {
+		batch = uint64(n)
+	}
+	r := NewIndexReverseSearch(f, key, start, end, WithIBatchSize(batch))
+	var ents []IndexEnt
+	for len(ents) < n {
+		e, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if e == nil {
+			break
+		}
+		ents = append(ents, e...)
+	}
+	if len(ents) > n {
+		ents = ents[:n]
+	}
+	return ents, nil
+}
+
+// IndexRange is a range of minors within an index major
+type IndexRange struct {
+	Major uint8
+	Start uint64
+	End   uint64
+}
+
+// IndexJoinEnt is an inode found in both ranges of an IndexJoin with its
+// minor in each range
+type IndexJoinEnt struct {
+	Inode uint64
+	Left  uint64
+	Right uint64
+}
+
+// IndexJoin returns the inodes found in two index ranges, such as a
+// size bucket and an age bucket.  The left range is read into a hash
+// table on the first call to Next() and the right range is streamed
+// against it, so left should be the range with fewer entries.
+type IndexJoin struct {
+	left  *IndexSearch
+	right *IndexSearch
+	// table of left inodes to their minors, nil until built
+	table map[uint64][]uint64
+}
+
+// NewIndexJoin creates a join of the left and right index ranges,
+// options apply to both searches
+func NewIndexJoin(f *os.File, left, right IndexRange, opts ...IOption) *IndexJoin {
+	return &IndexJoin{
+		left:  NewIndexSearch(f, left.Major, left.Start, left.End, opts...),
+		right: NewIndexSearch(f, right.Major, right.Start, right.End, opts...),
+	}
+}
+
+// Next gets the next batch of joined entries in right range order,
+// returns nil when complete
+func (j *IndexJoin) Next() ([]IndexJoinEnt, error) {
+	if j.table == nil {
+		j.table = make(map[uint64][]uint64)
+		for {
+			ents, err := j.left.Next()
+			if err != nil {
+				j.table = nil
+				return nil, err
+			}
+			if ents == nil {
+				break
+			}
+			for _, e := range ents {
+				j.table[e.Inode] = append(j.table[e.Inode], e.Value)
+			}
+		}
+	}
+
+	for {
+		ents, err := j.right.Next()
+		if err != nil {
+			return nil, err
+		}
+		if ents == nil {
+			return nil, nil
+		}
+
+		var joined []IndexJoinEnt
+		for _, e := range ents {
+			for _, v := range j.table[e.Inode] {
+				joined = append(joined, IndexJoinEnt{
+					Inode: e.Inode,
+					Left:  v,
+					Right: e.Value,
+				})
+			}
+		}
+		if len(joined) > 0 {
+			return joined, nil
+		}
+	}
+}
+
+// IndexBucket is the number of index entries with minors from Start to
+// End inclusive
+type IndexBucket struct {
+	Start uint64
+	End   uint64
+	Count uint64
+}
+
+// IndexHistogram counts the entries of index major key between start and
+// end in buckets of width minors starting at start.  A width of 0 or 1
+// counts each distinct minor.  Only buckets with entries are returned.
+func IndexHistogram(f *os.File, key uint8, start, end, width uint64, opts ...IOption) ([]IndexBucket, error) {
+	if width == 0 {
+		width = 1
+	}
+
+	var buckets []IndexBucket
+	s := NewIndexSearch(f, key, start, end, opts...)
+	for {
+		ents, err := s.Next()
+		if err != nil {
+			return nil, err
+		}
+		if ents == nil {
+			return buckets, nil
+		}
+
+		for _, e := range ents {
+			n := len(buckets)
+			if n > 0 && e.Value <= buckets[n-1].End {
+				buckets[n-1].Count++
+				continue
+			}
+
+			lo := start + (e.Value-start)/width*width
+			hi := end
+			if end-lo >= width {
+				hi = lo + width - 1
+			}
+			buckets = append(buckets, IndexBucket{Start: lo, End: hi, Count: 1})
+		}
+	}
+}
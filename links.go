@@ -0,0 +1,150 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"os"
+)
+
+const (
+	// linksBufsize is the default referring entries buffer size
+	linksBufsize = 64 * 1024
+	// linksBufMin holds one entry with the longest name and padding
+	linksBufMin = direntSize + 256 + 8
+	// linksCacheSize is the number of parent directory paths cached by
+	// the default resolver
+	linksCacheSize = 1024
+)
+
+// Link is a directory entry referring to an inode
+type Link struct {
+	// Parent is the inode of the directory containing the entry
+	Parent uint64
+	// Pos is the entry position within the parent directory
+	Pos uint64
+	// Name is the entry name
+	Name string
+	// Path is the full path of the entry relative to the filesystem root
+	Path string
+}
+
+// Links iterates over the directory entries referring to an inode.
+// Entries are read in batches that fit in the buffer, so inodes with
+// millions of hard links are read without holding them all at once.
+type Links struct {
+	dirfd    *os.File
+	ino      uint64
+	buf      []byte
+	limit    int
+	count    int
+	dirIno   uint64
+	dirPos   uint64
+	done     bool
+	resolver *PathResolver
+}
+
+// LOption sets various options for LinksOf
+type LOption func(*Links)
+
+// WithLinksBufSize sets the size of the buffer for reading entries,
+// which bounds the number of entries returned per Next() call
+func WithLinksBufSize(size int) LOption {
+	return func(l *Links) {
+		if size < linksBufMin {
+			size = linksBufMin
+		}
+		l.buf = make([]byte, size)
+	}
+}
+
+// WithLinksLimit sets the max number of links returned in total
+func WithLinksLimit(n int) LOption {
+	return func(l *Links) {
+		l.limit = n
+	}
+}
+
+// WithLinksResolver sets the resolver used for the parent directory
+// paths, so that it can be shared between iterators
+func WithLinksResolver(r *PathResolver) LOption {
+	return func(l *Links) {
+		l.resolver = r
+	}
+}
+
+// LinksOf creates an iterator over the links to ino
+// An open file within scoutfs is supplied for ioctls
+// (usually just the base mount point directory)
+func LinksOf(dirfd *os.File, ino uint64, opts ...LOption) *Links {
+	l := &Links{
+		dirfd: dirfd,
+		ino:   ino,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.buf == nil {
+		l.buf = make([]byte, linksBufsize)
+	}
+	if l.resolver == nil {
+		l.resolver = NewPathResolver(dirfd, WithResolverCacheSize(linksCacheSize))
+	}
+
+	return l
+}
+
+// Next gets the next batch of links, returns nil when complete
+func (l *Links) Next() ([]Link, error) {
+	if l.done || (l.limit > 0 && l.count >= l.limit) {
+		return nil, nil
+	}
+
+	ents, isLast, err := readParents(l.dirfd, l.ino, l.dirIno, l.dirPos, l.buf)
+	if err != nil {
+		return nil, err
+	}
+	if len(ents) == 0 {
+		l.done = true
+		return nil, nil
+	}
+
+	last := ents[len(ents)-1]
+	l.dirIno, l.dirPos = last.Ino, last.Pos+1
+	if l.dirPos == 0 {
+		l.dirIno++
+		if l.dirIno == 0 {
+			isLast = true
+		}
+	}
+	l.done = isLast
+
+	if l.limit > 0 && len(ents) > l.limit-l.count {
+		ents = ents[:l.limit-l.count]
+	}
+	l.count += len(ents)
+
+	links := make([]Link, len(ents))
+	for i, e := range ents {
+		dir, err := l.resolver.Path(e.Ino)
+		if err != nil {
+			return nil, err
+		}
+		path := e.Ent
+		if dir != "" {
+			path = dir + "/" + e.Ent
+		}
+		links[i] = Link{
+			Parent: e.Ino,
+			Pos:    e.Pos,
+			Name:   e.Ent,
+			Path:   path,
+		}
+	}
+
+	return links, nil
+}
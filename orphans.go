@@ -0,0 +1,182 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// orphanSamples is the default number of sample inodes reported per state
+const orphanSamples = 16
+
+// InodeState is the reachability of an inode found in the meta_seq index
+type InodeState int
+
+const (
+	// InodeReachable inodes have a path
+	InodeReachable InodeState = iota
+	// InodeOrphaned inodes have no path but can still be opened, such
+	// as unlinked files that are still open
+	InodeOrphaned
+	// InodeMissing inodes have no path and can not be opened, usually
+	// removed since being found
+	InodeMissing
+)
+
+func (s InodeState) String() string {
+	switch s {
+	case InodeReachable:
+		return "reachable"
+	case InodeOrphaned:
+		return "orphaned"
+	case InodeMissing:
+		return "missing"
+	}
+	return "unknown"
+}
+
+// OrphanReport counts the inodes in each state with samples of the inodes
+// that are not reachable
+type OrphanReport struct {
+	Scanned         uint64
+	Reachable       uint64
+	Orphaned        uint64
+	Missing         uint64
+	OrphanedSamples []uint64
+	MissingSamples  []uint64
+}
+
+// String returns the report summary
+func (r OrphanReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "scanned %v: reachable %v, orphaned %v, missing %v\n",
+		r.Scanned, r.Reachable, r.Orphaned, r.Missing)
+	if len(r.OrphanedSamples) > 0 {
+		fmt.Fprintf(&b, "orphaned: %v\n", r.OrphanedSamples)
+	}
+	if len(r.MissingSamples) > 0 {
+		fmt.Fprintf(&b, "missing: %v\n", r.MissingSamples)
+	}
+	return b.String()
+}
+
+// OrphanScanner finds inodes without a path
+type OrphanScanner struct {
+	dirfd   *os.File
+	samples int
+	batch   uint32
+}
+
+// OSOption sets various options for NewOrphanScanner
+type OSOption func(*OrphanScanner)
+
+// WithOrphanSamples sets the max number of sample inodes reported for
+// each unreachable state
+func WithOrphanSamples(n int) OSOption {
+	return func(s *OrphanScanner) {
+		s.samples = n
+	}
+}
+
+// WithOrphanBatchSize sets the number of inodes read per meta_seq query
+func WithOrphanBatchSize(size uint32) OSOption {
+	return func(s *OrphanScanner) {
+		s.batch = size
+	}
+}
+
+// NewOrphanScanner creates a scanner for the filesystem of dirfd
+// An open file within scoutfs is supplied for ioctls
+// (usually just the base mount point directory)
+func NewOrphanScanner(dirfd *os.File, opts ...OSOption) *OrphanScanner {
+	s := &OrphanScanner{
+		dirfd:   dirfd,
+		samples: orphanSamples,
+		batch:   1024,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Classify returns the state of ino.  Inodes with a path are reachable,
+// otherwise inodes that can be opened by handle are orphaned and those
+// that can't are missing.
+func (s *OrphanScanner) Classify(ino uint64) (InodeState, error) {
+	if ino == rootIno {
+		return InodeReachable, nil
+	}
+
+	_, err := InoToPath(s.dirfd, ino)
+	if err == nil {
+		return InodeReachable, nil
+	}
+	if err != syscall.ENOENT {
+		return 0, fmt.Errorf("path of inode %v: %v", ino, err)
+	}
+
+	fd, err := OpenByHandle(s.dirfd, ino,
+		syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_NOFOLLOW)
+	switch err {
+	case nil:
+		syscall.Close(int(fd))
+		return InodeOrphaned, nil
+	case syscall.ELOOP, syscall.ENXIO:
+		// exists but is a symlink or special file that can't be opened
+		return InodeOrphaned, nil
+	case syscall.ENOENT, syscall.ESTALE:
+		return InodeMissing, nil
+	}
+	return 0, fmt.Errorf("open inode %v: %v", ino, err)
+}
+
+// Scan classifies every inode in the meta_seq index
+func (s *OrphanScanner) Scan() (OrphanReport, error) {
+	var r OrphanReport
+	min := InodesEntry{}
+	max := InodesEntry{Major: max64, Minor: max32, Ino: max64}
+	q := NewQuery(s.dirfd, ByMSeq(min, max), WithBatchSize(s.batch))
+
+	for {
+		ents, err := q.Next()
+		if err != nil {
+			return r, fmt.Errorf("query meta_seq: %v", err)
+		}
+		if len(ents) == 0 {
+			return r, nil
+		}
+
+		for _, e := range ents {
+			state, err := s.Classify(e.Ino)
+			if err != nil {
+				return r, err
+			}
+
+			r.Scanned++
+			switch state {
+			case InodeReachable:
+				r.Reachable++
+			case InodeOrphaned:
+				r.Orphaned++
+				if len(r.OrphanedSamples) < s.samples {
+					r.OrphanedSamples = append(r.OrphanedSamples, e.Ino)
+				}
+			case InodeMissing:
+				r.Missing++
+				if len(r.MissingSamples) < s.samples {
+					r.MissingSamples = append(r.MissingSamples, e.Ino)
+				}
+			}
+		}
+	}
+}
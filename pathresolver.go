@@ -0,0 +1,223 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"container/list"
+	"os"
+	"sync"
+	"syscall"
+)
+
+const (
+	// rootIno is the inode number of the filesystem root
+	rootIno = 1
+	// resolverCacheSize is the default number of cached directories
+	resolverCacheSize = 64 * 1024
+	// resolverBufsize is the default referring entries buffer size
+	resolverBufsize = 16 * 1024
+)
+
+// PathResolver converts inode numbers to paths by walking parent
+// directories up to the root, caching the paths of directories.  The
+// cache is bounded with least recently used directories evicted first.
+// Paths are relative to the filesystem root, as with InoToPath, and
+// inodes with multiple links resolve to the path of one of them.
+// Resolving is safe for concurrent use, calls are serialized.
+type PathResolver struct {
+	mu    sync.Mutex
+	dirfd *os.File
+	max   int
+	buf   []byte
+	lru   *list.List
+	cache map[uint64]*list.Element
+	// children of each directory with cached paths, kept after the
+	// parent is evicted so that invalidation still reaches them
+	children map[uint64]map[uint64]struct{}
+}
+
+type resolverEntry struct {
+	ino    uint64
+	parent uint64
+	path   string
+}
+
+// PROption sets various options for NewPathResolver
+type PROption func(*PathResolver)
+
+// WithResolverCacheSize sets the max number of cached directory paths
+func WithResolverCacheSize(n int) PROption {
+	return func(r *PathResolver) {
+		r.max = n
+	}
+}
+
+// WithResolverBufSize sets the size of the buffer used to read parent
+// entries, it must hold at least one entry with the longest name
+func WithResolverBufSize(n int) PROption {
+	return func(r *PathResolver) {
+		r.buf = make([]byte, n)
+	}
+}
+
+// NewPathResolver creates a resolver for the filesystem of dirfd
+// An open file within scoutfs is supplied for ioctls
+// (usually just the base mount point directory)
+func NewPathResolver(dirfd *os.File, opts ...PROption) *PathResolver {
+	r := &PathResolver{
+		dirfd:    dirfd,
+		max:      resolverCacheSize,
+		lru:      list.New(),
+		cache:    make(map[uint64]*list.Element),
+		children: make(map[uint64]map[uint64]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.buf == nil {
+		r.buf = make([]byte, resolverBufsize)
+	}
+
+	return r
+}
+
+// Path returns a path of ino
+func (r *PathResolver) Path(ino uint64) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ino == rootIno {
+		return "", nil
+	}
+
+	// walk up until reaching the root or a cached directory
+	var chain []Parent
+	var prefix string
+	cur := ino
+	for cur != rootIno {
+		if e, ok := r.cache[cur]; ok {
+			r.lru.MoveToFront(e)
+			prefix = e.Value.(*resolverEntry).path
+			break
+		}
+
+		p, err := r.parent(cur)
+		if err != nil {
+			return "", err
+		}
+		chain = append(chain, p)
+		cur = p.Ino
+	}
+
+	// build down, caching the directories
+	path := prefix
+	for i := len(chain) - 1; i >= 0; i-- {
+		if path == "" {
+			path = chain[i].Ent
+		} else {
+			path = path + "/" + chain[i].Ent
+		}
+		dir := ino
+		if i > 0 {
+			dir = chain[i-1].Ino
+		}
+		if chain[i].Type == syscall.DT_DIR {
+			r.add(dir, chain[i].Ino, path)
+		}
+	}
+
+	return path, nil
+}
+
+// parent returns the first entry referring to ino
+func (r *PathResolver) parent(ino uint64) (Parent, error) {
+	ents, _, err := readParents(r.dirfd, ino, 0, 0, r.buf)
+	if err != nil {
+		return Parent{}, err
+	}
+	if len(ents) == 0 {
+		return Parent{}, syscall.ENOENT
+	}
+	return ents[0], nil
+}
+
+func (r *PathResolver) add(ino, parent uint64, path string) {
+	if r.max <= 0 {
+		return
+	}
+	if e, ok := r.cache[ino]; ok {
+		r.remove(e)
+	}
+
+	r.cache[ino] = r.lru.PushFront(&resolverEntry{
+		ino:    ino,
+		parent: parent,
+		path:   path,
+	})
+	kids, ok := r.children[parent]
+	if !ok {
+		kids = make(map[uint64]struct{})
+		r.children[parent] = kids
+	}
+	kids[ino] = struct{}{}
+
+	for r.lru.Len() > r.max {
+		r.remove(r.lru.Back())
+	}
+}
+
+func (r *PathResolver) remove(e *list.Element) {
+	ent := e.Value.(*resolverEntry)
+	r.lru.Remove(e)
+	delete(r.cache, ent.ino)
+	if kids, ok := r.children[ent.parent]; ok {
+		delete(kids, ent.ino)
+		if len(kids) == 0 {
+			delete(r.children, ent.parent)
+		}
+	}
+}
+
+// Invalidate drops the cached path of directory ino and of all the
+// cached directories below it
+func (r *PathResolver) Invalidate(ino uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.invalidate(ino)
+}
+
+func (r *PathResolver) invalidate(ino uint64) {
+	if e, ok := r.cache[ino]; ok {
+		r.remove(e)
+	}
+	for kid := range r.children[ino] {
+		r.invalidate(kid)
+	}
+	delete(r.children, ino)
+}
+
+// InvalidateEntries drops the cached paths of the changed inodes
+// returned by a meta_seq Query, and of the directories below them.
+// Renaming a directory updates its meta_seq, so feeding every batch of
+// a meta_seq Query keeps the cached paths current.
+func (r *PathResolver) InvalidateEntries(ents []InodesEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range ents {
+		if _, ok := r.cache[e.Ino]; ok || r.children[e.Ino] != nil {
+			r.invalidate(e.Ino)
+		}
+	}
+}
+
+// Len returns the number of cached directory paths
+func (r *PathResolver) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lru.Len()
+}
@@ -0,0 +1,625 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Policy is a parsed expression selecting inodes, such as
+//
+//	size > 1G and offline_blocks == 0 and project == 42 and crtime < now-90d
+//
+// Expressions combine comparisons with and, or, not and parentheses.
+// Comparisons are ==, !=, <, <=, > and >=.  Numbers may have an upper
+// case size suffix (K, M, G, T, P in powers of 1024) or a lower case
+// duration suffix (s, m, h, d, w).  Times may be offset by durations, as
+// in now-90d.  Numbers are unsigned 64 bit values, differences may be
+// negative.
+//
+// Fields are ino, size, uid, gid, nlink, mode (permission bits), type
+// ("file", "dir", "symlink", "fifo", "socket", "char" or "block"),
+// project, meta_seq, data_seq, data_version, online_blocks,
+// offline_blocks, and the times atime, mtime, ctime and crtime.
+//
+// Functions are xattr("name") which is true if the inode has the xattr,
+// index(major) which compares true if any of the inode's .indx. minors in
+// major compares true, and totl(id1, id2, id3) which is the value of the
+// inode's .totl. xattr for the ids, or 0 without one.
+type Policy struct {
+	src  string
+	root policyExpr
+}
+
+// ParsePolicy parses a policy expression
+func ParsePolicy(s string) (*Policy, error) {
+	toks, err := lexPolicy(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &policyParser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, p.errorf("unexpected %q", p.peek().text)
+	}
+
+	kind, err := root.check()
+	if err != nil {
+		return nil, err
+	}
+	if kind != kindBool {
+		return nil, fmt.Errorf("policy must be a condition, not a %v", kind)
+	}
+
+	return &Policy{src: s, root: root}, nil
+}
+
+// String returns the policy expression as parsed
+func (p *Policy) String() string {
+	return p.src
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+)
+
+type policyToken struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+func lexPolicy(s string) ([]policyToken, error) {
+	var toks []policyToken
+	i := 0
+	for i < len(s) {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) ||
+				unicode.IsDigit(rune(s[j])) || s[j] == '_') {
+				j++
+			}
+			toks = append(toks, policyToken{kind: tokIdent, text: s[i:j], pos: i})
+			i = j
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			for j < len(s) && unicode.IsLetter(rune(s[j])) {
+				j++
+			}
+			toks = append(toks, policyToken{kind: tokNumber, text: s[i:j], pos: i})
+			i = j
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string at %v", i)
+			}
+			str, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at %v: %v", i, err)
+			}
+			toks = append(toks, policyToken{kind: tokString, text: str, pos: i})
+			i = j + 1
+		default:
+			op := ""
+			for _, o := range []string{"==", "!=", "<=", ">=", "<", ">", "(", ")", ",", "+", "-"} {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %v", c, i)
+			}
+			toks = append(toks, policyToken{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(toks, policyToken{kind: tokEOF, pos: len(s)}), nil
+}
+
+type policyParser struct {
+	toks []policyToken
+	pos  int
+}
+
+func (p *policyParser) peek() policyToken {
+	return p.toks[p.pos]
+}
+
+func (p *policyParser) next() policyToken {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *policyParser) isWord(w string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && strings.EqualFold(t.text, w)
+}
+
+func (p *policyParser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == op
+}
+
+func (p *policyParser) expect(op string) error {
+	if !p.isOp(op) {
+		return p.errorf("expected %q", op)
+	}
+	p.next()
+	return nil
+}
+
+func (p *policyParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("policy at %v: %v", p.peek().pos, fmt.Sprintf(format, args...))
+}
+
+func (p *policyParser) parseOr() (policyExpr, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isWord("or") {
+		p.next()
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = &policyBinary{op: "or", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *policyParser) parseAnd() (policyExpr, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isWord("and") {
+		p.next()
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l = &policyBinary{op: "and", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *policyParser) parseNot() (policyExpr, error) {
+	if p.isWord("not") {
+		p.next()
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &policyNot{x: x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *policyParser) parseCompare() (policyExpr, error) {
+	l, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.isOp(op) {
+			p.next()
+			r, err := p.parseSum()
+			if err != nil {
+				return nil, err
+			}
+			return &policyBinary{op: op, l: l, r: r}, nil
+		}
+	}
+	return l, nil
+}
+
+func (p *policyParser) parseSum() (policyExpr, error) {
+	l, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+") || p.isOp("-") {
+		op := p.next().text
+		r, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		l = &policyBinary{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *policyParser) parseTerm() (policyExpr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		v, err := parsePolicyNumber(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("policy at %v: %v", t.pos, err)
+		}
+		return &policyLit{v: v}, nil
+	case tokString:
+		return &policyLit{v: policyValue{kind: kindString, s: t.text}}, nil
+	case tokIdent:
+		name := strings.ToLower(t.text)
+		if p.isOp("(") {
+			return p.parseCall(name)
+		}
+		if name == "now" {
+			return &policyNow{}, nil
+		}
+		if _, ok := policyFields[name]; !ok {
+			return nil, fmt.Errorf("policy at %v: unknown field %q", t.pos, t.text)
+		}
+		return &policyField{name: name}, nil
+	case tokOp:
+		if t.text == "(" {
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		}
+	}
+	if t.kind == tokEOF {
+		return nil, fmt.Errorf("policy at %v: unexpected end", t.pos)
+	}
+	return nil, fmt.Errorf("policy at %v: unexpected %q", t.pos, t.text)
+}
+
+func (p *policyParser) parseCall(name string) (policyExpr, error) {
+	pos := p.peek().pos
+	p.next()
+
+	var args []policyToken
+	for !p.isOp(")") {
+		if len(args) > 0 {
+			err := p.expect(",")
+			if err != nil {
+				return nil, err
+			}
+		}
+		t := p.next()
+		if t.kind != tokNumber && t.kind != tokString {
+			return nil, fmt.Errorf("policy at %v: %v arguments must be literals", t.pos, name)
+		}
+		args = append(args, t)
+	}
+	p.next()
+
+	c := &policyCall{name: name}
+	switch name {
+	case "xattr":
+		if len(args) != 1 || args[0].kind != tokString {
+			return nil, fmt.Errorf("policy at %v: xattr takes one string", pos)
+		}
+		c.xattr = args[0].text
+		if IsScoutfsXattr(c.xattr) {
+			// tags may be written in any order, compare canonical names
+			x, err := ParseXattrName(c.xattr)
+			if err != nil {
+				return nil, fmt.Errorf("policy at %v: %v", pos, err)
+			}
+			c.xattr = x.String()
+		}
+	case "index":
+		if len(args) != 1 || args[0].kind != tokNumber {
+			return nil, fmt.Errorf("policy at %v: index takes one major", pos)
+		}
+		v, err := strconv.ParseUint(args[0].text, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("policy at %v: invalid index major %q", pos, args[0].text)
+		}
+		c.major = uint8(v)
+	case "totl":
+		if len(args) != 3 {
+			return nil, fmt.Errorf("policy at %v: totl takes three ids", pos)
+		}
+		for i, a := range args {
+			v, err := strconv.ParseUint(a.text, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("policy at %v: invalid totl id %q", pos, a.text)
+			}
+			c.totl[i] = v
+		}
+	default:
+		return nil, fmt.Errorf("policy at %v: unknown function %q", pos, name)
+	}
+	return c, nil
+}
+
+// size suffixes are upper case so that they can't be mistaken for the
+// lower case duration suffixes, such as M for MiB and m for minutes
+var policySizeSuffix = map[string]float64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+	"P": 1 << 50,
+}
+
+var policyDurSuffix = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+func parsePolicyNumber(s string) (policyValue, error) {
+	i := strings.IndexFunc(s, unicode.IsLetter)
+	if i < 0 {
+		i = len(s)
+	}
+	num, suffix := s[:i], s[i:]
+
+	if !strings.Contains(num, ".") && suffix == "" {
+		v, err := strconv.ParseUint(num, 10, 64)
+		if err != nil {
+			return policyValue{}, fmt.Errorf("invalid number %q", s)
+		}
+		return numberValue(v), nil
+	}
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return policyValue{}, fmt.Errorf("invalid number %q", s)
+	}
+	if m, ok := policySizeSuffix[suffix]; ok {
+		f *= m
+		if f >= math.Exp2(64) {
+			return policyValue{}, fmt.Errorf("number %q out of range", s)
+		}
+		return numberValue(uint64(f)), nil
+	}
+	if d, ok := policyDurSuffix[suffix]; ok {
+		f *= float64(d)
+		if f >= math.MaxInt64 {
+			return policyValue{}, fmt.Errorf("duration %q out of range", s)
+		}
+		return policyValue{kind: kindDuration, d: time.Duration(f)}, nil
+	}
+	return policyValue{}, fmt.Errorf("unknown suffix in %q, sizes are K, M, G, T or P and durations are s, m, h, d or w", s)
+}
+
+type valueKind int
+
+const (
+	kindBool valueKind = iota
+	kindNumber
+	kindTime
+	kindDuration
+	kindString
+)
+
+func (k valueKind) String() string {
+	switch k {
+	case kindBool:
+		return "condition"
+	case kindNumber:
+		return "number"
+	case kindTime:
+		return "time"
+	case kindDuration:
+		return "duration"
+	case kindString:
+		return "string"
+	}
+	return "unknown"
+}
+
+type policyValue struct {
+	kind valueKind
+	b    bool
+	// n is the magnitude of a number, negative if neg is set
+	n   uint64
+	neg bool
+	t   time.Time
+	d   time.Duration
+	s   string
+}
+
+func numberValue(n uint64) policyValue {
+	return policyValue{kind: kindNumber, n: n}
+}
+
+// addNumbers returns l + r, or l - r if sub is set, saturating at the
+// largest magnitude
+func addNumbers(l, r policyValue, sub bool) policyValue {
+	rneg := r.neg != sub
+	v := policyValue{kind: kindNumber}
+	switch {
+	case l.neg == rneg:
+		v.n = l.n + r.n
+		if v.n < l.n {
+			v.n = math.MaxUint64
+		}
+		v.neg = l.neg
+	case l.n >= r.n:
+		v.n = l.n - r.n
+		v.neg = l.neg
+	default:
+		v.n = r.n - l.n
+		v.neg = rneg
+	}
+	if v.n == 0 {
+		v.neg = false
+	}
+	return v
+}
+
+func cmpNumbers(l, r policyValue) int {
+	switch {
+	case l.neg && !r.neg:
+		return -1
+	case !l.neg && r.neg:
+		return 1
+	}
+
+	c := 0
+	switch {
+	case l.n < r.n:
+		c = -1
+	case l.n > r.n:
+		c = 1
+	}
+	if l.neg {
+		c = -c
+	}
+	return c
+}
+
+// policyFields are the inode fields and their kinds
+var policyFields = map[string]valueKind{
+	"ino":            kindNumber,
+	"size":           kindNumber,
+	"uid":            kindNumber,
+	"gid":            kindNumber,
+	"nlink":          kindNumber,
+	"mode":           kindNumber,
+	"type":           kindString,
+	"project":        kindNumber,
+	"meta_seq":       kindNumber,
+	"data_seq":       kindNumber,
+	"data_version":   kindNumber,
+	"online_blocks":  kindNumber,
+	"offline_blocks": kindNumber,
+	"atime":          kindTime,
+	"mtime":          kindTime,
+	"ctime":          kindTime,
+	"crtime":         kindTime,
+}
+
+type policyExpr interface {
+	check() (valueKind, error)
+	eval(in *policyInode) (policyValue, error)
+}
+
+type policyLit struct {
+	v policyValue
+}
+
+type policyNow struct{}
+
+type policyField struct {
+	name string
+}
+
+type policyCall struct {
+	name  string
+	xattr string
+	major uint8
+	totl  [3]uint64
+}
+
+type policyNot struct {
+	x policyExpr
+}
+
+type policyBinary struct {
+	op string
+	l  policyExpr
+	r  policyExpr
+}
+
+func (e *policyLit) check() (valueKind, error) { return e.v.kind, nil }
+
+func (e *policyNow) check() (valueKind, error) { return kindTime, nil }
+
+func (e *policyField) check() (valueKind, error) { return policyFields[e.name], nil }
+
+func (e *policyCall) check() (valueKind, error) {
+	if e.name == "xattr" {
+		return kindBool, nil
+	}
+	return kindNumber, nil
+}
+
+func (e *policyNot) check() (valueKind, error) {
+	k, err := e.x.check()
+	if err != nil {
+		return 0, err
+	}
+	if k != kindBool {
+		return 0, fmt.Errorf("not requires a condition, not a %v", k)
+	}
+	return kindBool, nil
+}
+
+func (e *policyBinary) check() (valueKind, error) {
+	lk, err := e.l.check()
+	if err != nil {
+		return 0, err
+	}
+	rk, err := e.r.check()
+	if err != nil {
+		return 0, err
+	}
+
+	switch e.op {
+	case "and", "or":
+		if lk != kindBool || rk != kindBool {
+			return 0, fmt.Errorf("%v requires conditions, not %v and %v", e.op, lk, rk)
+		}
+		return kindBool, nil
+	case "+", "-":
+		switch {
+		case lk == kindNumber && rk == kindNumber:
+			return kindNumber, nil
+		case lk == kindDuration && rk == kindDuration:
+			return kindDuration, nil
+		case lk == kindTime && rk == kindDuration:
+			return kindTime, nil
+		}
+		return 0, fmt.Errorf("can not %v %v and %v", e.op, lk, rk)
+	}
+
+	if lk != rk {
+		return 0, fmt.Errorf("can not compare %v %v %v", lk, e.op, rk)
+	}
+	if (lk == kindString || lk == kindBool) && e.op != "==" && e.op != "!=" {
+		return 0, fmt.Errorf("%v only supports == and !=", lk)
+	}
+	if c, ok := e.r.(*policyCall); ok && c.name == "index" {
+		return 0, fmt.Errorf("index must be on the left of %v", e.op)
+	}
+	return kindBool, nil
+}
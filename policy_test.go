@@ -0,0 +1,251 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePolicyNumber(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    policyValue
+		wantErr bool
+	}{
+		{s: "0", want: numberValue(0)},
+		{s: "42", want: numberValue(42)},
+		{s: "18446744073709551615", want: numberValue(max64)},
+		{s: "9223372036854775808", want: numberValue(1 << 63)},
+		{s: "1K", want: numberValue(1 << 10)},
+		{s: "1.5M", want: numberValue(3 << 19)},
+		{s: "2G", want: numberValue(2 << 30)},
+		{s: "1T", want: numberValue(1 << 40)},
+		{s: "16P", want: numberValue(1 << 54)},
+		{s: "1s", want: policyValue{kind: kindDuration, d: time.Second}},
+		{s: "1m", want: policyValue{kind: kindDuration, d: time.Minute}},
+		{s: "1.5h", want: policyValue{kind: kindDuration, d: 90 * time.Minute}},
+		{s: "90d", want: policyValue{kind: kindDuration, d: 90 * 24 * time.Hour}},
+		{s: "2w", want: policyValue{kind: kindDuration, d: 14 * 24 * time.Hour}},
+		{s: "18446744073709551616", wantErr: true},
+		{s: "16384P", wantErr: true},
+		{s: "300000w", wantErr: true},
+		{s: "1k", wantErr: true},
+		{s: "1g", wantErr: true},
+		{s: "1D", wantErr: true},
+		{s: "1KB", wantErr: true},
+		{s: "1x", wantErr: true},
+		{s: "1.2.3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			v, err := parsePolicyNumber(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePolicyNumber(%q) err = %v, wantErr %v", tt.s, err, tt.wantErr)
+			}
+			if v != tt.want {
+				t.Errorf("parsePolicyNumber(%q) = %+v, want %+v", tt.s, v, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		wantErr bool
+	}{
+		{"compare", "size > 1G", false},
+		{"and or not", "size > 1G and not (uid == 0 or gid == 0)", false},
+		{"case insensitive words", "SIZE > 1 AND Uid == 0", false},
+		{"time offset", "crtime < now-90d", false},
+		{"time plus duration", "mtime + 1d < now", false},
+		{"duration compare", "now - 1d > now - 2d", false},
+		{"number arithmetic", "size - 1K >= 0", false},
+		{"string field", `type == "file"`, false},
+		{"xattr", `xattr("scoutfs.hide.data")`, false},
+		{"index", "index(7) > 100", false},
+		{"totl", "totl(1, 2, 3) > 0", false},
+		{"condition equality", "(size > 0) == (uid > 0)", false},
+
+		{"empty", "", true},
+		{"not a condition", "size", true},
+		{"unknown field", "bogus > 0", true},
+		{"unknown function", "bogus(1) > 0", true},
+		{"trailing tokens", "size > 0 0", true},
+		{"unbalanced parens", "(size > 0", true},
+		{"unterminated string", `type == "file`, true},
+		{"unexpected character", "size > 0 & uid == 0", true},
+		{"lowercase size suffix", "size > 1k", true},
+		{"number vs time", "mtime > 5", true},
+		{"number vs duration", "size > 1d", true},
+		{"time plus time", "mtime + now < now", true},
+		{"number plus duration", "size + 1d > 0", true},
+		{"and of numbers", "size and uid", true},
+		{"not of number", "not size", true},
+		{"string ordering", `type < "file"`, true},
+		{"condition ordering", "(size > 0) < (uid > 0)", true},
+		{"index on right", "100 < index(7)", true},
+		{"xattr number", "xattr(1)", true},
+		{"xattr invalid scoutfs name", `xattr("scoutfs.totl.n.1")`, true},
+		{"index major range", "index(256) > 0", true},
+		{"index string", `index("a") > 0`, true},
+		{"totl arity", "totl(1, 2) > 0", true},
+		{"call field argument", "totl(1, 2, size) > 0", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePolicy(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePolicy(%q) err = %v, wantErr %v", tt.s, err, tt.wantErr)
+			}
+			if err == nil && p.String() != tt.s {
+				t.Errorf("String() = %q, want %q", p.String(), tt.s)
+			}
+		})
+	}
+}
+
+func TestPolicyEvalLiterals(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"18446744073709551615 > 9223372036854775808", true},
+		{"9223372036854775808 > 9223372036854775807", true},
+		{"18446744073709551615 == 16383P + 1023T + 1023G + 1023M + 1023K + 1023", true},
+		{"0 - 1 < 0", true},
+		{"0 - 1 < 0 - 0", true},
+		{"1 - 2 == 0 - 1", true},
+		{"0 - 18446744073709551615 < 0 - 1", true},
+		{"18446744073709551615 + 1 == 18446744073709551615", true},
+		{"1 - 2 + 2 == 1", true},
+		{"1K == 1024", true},
+		{"1M > 1K", true},
+		{"1m > 1s", true},
+		{"1d == 24h", true},
+		{"now - 1d < now", true},
+		{"now == now", true},
+		{`"a" != "b"`, true},
+		{"1 > 2 or 2 > 1", true},
+		{"1 > 2 and 2 > 1", false},
+		{"not 1 > 2", true},
+		{"(1 > 2) == (3 > 4)", true},
+	}
+
+	in := &policyInode{now: time.Unix(1700000000, 0)}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			p, err := ParsePolicy(tt.s)
+			if err != nil {
+				t.Fatalf("ParsePolicy(%q): %v", tt.s, err)
+			}
+			v, err := p.root.eval(in)
+			if err != nil {
+				t.Fatalf("eval(%q): %v", tt.s, err)
+			}
+			if v.b != tt.want {
+				t.Errorf("eval(%q) = %v, want %v", tt.s, v.b, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareRange(t *testing.T) {
+	tests := []struct {
+		op     string
+		v      policyValue
+		lo, hi uint64
+		ok     bool
+	}{
+		{"==", numberValue(5), 5, 5, true},
+		{">", numberValue(5), 6, max64, true},
+		{">", numberValue(max64), 1, 0, true},
+		{">=", numberValue(1 << 63), 1 << 63, max64, true},
+		{"<", numberValue(5), 0, 4, true},
+		{"<", numberValue(0), 1, 0, true},
+		{"<=", numberValue(max64), 0, max64, true},
+		{"!=", numberValue(5), 0, 0, false},
+		{">", policyValue{kind: kindNumber, n: 1, neg: true}, 0, max64, true},
+		{"<", policyValue{kind: kindNumber, n: 1, neg: true}, 1, 0, true},
+		{"==", policyValue{kind: kindNumber, n: 1, neg: true}, 1, 0, true},
+	}
+
+	for _, tt := range tests {
+		lo, hi, ok := compareRange(tt.op, tt.v)
+		if lo != tt.lo || hi != tt.hi || ok != tt.ok {
+			t.Errorf("compareRange(%q, %+v) = %v, %v, %v, want %v, %v, %v",
+				tt.op, tt.v, lo, hi, ok, tt.lo, tt.hi, tt.ok)
+		}
+	}
+}
+
+func TestRangeFraction(t *testing.T) {
+	tests := []struct {
+		lo, hi, first, last uint64
+		want                float64
+	}{
+		{0, max64, 10, 19, 1},
+		{10, 14, 10, 19, 0.5},
+		{15, max64, 10, 19, 0.5},
+		{0, 9, 10, 19, 0},
+		{20, 30, 10, 19, 0},
+		{1, 0, 10, 19, 0},
+		{5, 5, 5, 5, 1},
+	}
+
+	for _, tt := range tests {
+		got := rangeFraction(tt.lo, tt.hi, tt.first, tt.last)
+		if got != tt.want {
+			t.Errorf("rangeFraction(%v, %v, %v, %v) = %v, want %v",
+				tt.lo, tt.hi, tt.first, tt.last, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyXattrTagOrder(t *testing.T) {
+	tests := []struct {
+		s     string
+		canon string
+		have  string
+		want  bool
+	}{
+		{`xattr("scoutfs.srch.hide.foo")`, "scoutfs.hide.srch.foo", "scoutfs.hide.srch.foo", true},
+		{`xattr("scoutfs.hide.srch.foo")`, "scoutfs.hide.srch.foo", "scoutfs.hide.srch.foo", true},
+		{`xattr("scoutfs.srch.hide.foo")`, "scoutfs.hide.srch.foo", "scoutfs.srch.foo", false},
+		{`xattr("scoutfs.totl.hide.n.1.2.3")`, "scoutfs.hide.totl.n.1.2.3", "scoutfs.hide.totl.n.1.2.3", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			p, err := ParsePolicy(tt.s)
+			if err != nil {
+				t.Fatalf("ParsePolicy(%q): %v", tt.s, err)
+			}
+			have, err := ParseXattrName(tt.have)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			in := &policyInode{xattrs: []XattrName{have}, listed: true}
+			v, err := p.root.eval(in)
+			if err != nil {
+				t.Fatalf("eval(%q): %v", tt.s, err)
+			}
+			if v.b != tt.want {
+				t.Errorf("eval(%q) with %q = %v, want %v", tt.s, tt.have, v.b, tt.want)
+			}
+
+			if c := p.root.(*policyCall); c.xattr != tt.canon {
+				t.Errorf("xattr name = %q, want %q", c.xattr, tt.canon)
+			}
+		})
+	}
+}
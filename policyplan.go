@@ -0,0 +1,833 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// PolicySource is the source of candidate inodes for a policy
+type PolicySource int
+
+const (
+	// PolicyNone is used when totals show that no inode can match
+	PolicyNone PolicySource = iota
+	// PolicyXattrSearch reads the inodes with a .srch. xattr
+	PolicyXattrSearch
+	// PolicyIndexRange reads the inodes in a range of an .indx. major
+	PolicyIndexRange
+	// PolicySeqWalk reads the inodes in a range of the meta_seq or
+	// data_seq index, all inodes for the full meta_seq range
+	PolicySeqWalk
+)
+
+func (s PolicySource) String() string {
+	switch s {
+	case PolicyNone:
+		return "none"
+	case PolicyXattrSearch:
+		return "xattr search"
+	case PolicyIndexRange:
+		return "index range"
+	case PolicySeqWalk:
+		return "seq walk"
+	}
+	return "unknown"
+}
+
+// PolicyPlan is the driving source chosen for a policy.  Every candidate
+// inode from the source is evaluated against the full policy.
+type PolicyPlan struct {
+	Source PolicySource
+	// Xattr is the search key for PolicyXattrSearch
+	Xattr string
+	// IndexMajor, IndexStart and IndexEnd are the range for
+	// PolicyIndexRange
+	IndexMajor uint8
+	IndexStart uint64
+	IndexEnd   uint64
+	// DataSeq walks data_seq instead of meta_seq for PolicySeqWalk
+	DataSeq  bool
+	SeqStart uint64
+	SeqEnd   uint64
+	// Estimate is the estimated fraction of inodes read from the source,
+	// from 0 to 1
+	Estimate float64
+	// Reason explains the choice
+	Reason string
+}
+
+// String returns the explanation of the plan
+func (p PolicyPlan) String() string {
+	switch p.Source {
+	case PolicyXattrSearch:
+		return fmt.Sprintf("%v %q: %v", p.Source, p.Xattr, p.Reason)
+	case PolicyIndexRange:
+		return fmt.Sprintf("%v major %v minors %v-%v: %v", p.Source,
+			p.IndexMajor, p.IndexStart, p.IndexEnd, p.Reason)
+	case PolicySeqWalk:
+		seq := "meta_seq"
+		if p.DataSeq {
+			seq = "data_seq"
+		}
+		return fmt.Sprintf("%v %v %v-%v: %v", p.Source, seq,
+			p.SeqStart, p.SeqEnd, p.Reason)
+	}
+	return fmt.Sprintf("%v: %v", p.Source, p.Reason)
+}
+
+// xattrSearchEstimate is the assumed fraction of inodes with a given
+// .srch. xattr, the search can't be counted without reading it
+const xattrSearchEstimate = 0.01
+
+// Plan picks the cheapest driving source for the policy from the
+// conditions that must all be true.  Each source is given an estimate
+// of the fraction of inodes it reads.  Ranges of an .indx. major, of
+// meta_seq or of data_seq are estimated from their width within the
+// populated part of the index, searches for a .srch. xattr are assumed
+// to be selective, and a totl condition that excludes 0 with no inodes
+// in the totals reads nothing.  Without a narrowing condition all inodes
+// are walked.
+// An open file within scoutfs is supplied for ioctls
+// (usually just the base mount point directory)
+func (p *Policy) Plan(f *os.File) (PolicyPlan, error) {
+	var conds []policyExpr
+	flattenAnd(p.root, &conds)
+
+	best := PolicyPlan{
+		Source:   PolicySeqWalk,
+		SeqEnd:   max64,
+		Estimate: 1,
+		Reason:   "no condition narrows the inodes",
+	}
+	for _, c := range conds {
+		plan, ok, err := planCondition(f, c)
+		if err != nil {
+			return PolicyPlan{}, err
+		}
+		if ok && (plan.Estimate < best.Estimate ||
+			(plan.Estimate == best.Estimate && plan.Source < best.Source)) {
+			best = plan
+		}
+	}
+	return best, nil
+}
+
+// rangeFraction returns the fraction of the populated range first to
+// last covered by lo to hi
+func rangeFraction(lo, hi, first, last uint64) float64 {
+	if lo < first {
+		lo = first
+	}
+	if hi > last {
+		hi = last
+	}
+	if lo > hi || first > last {
+		return 0
+	}
+	return (float64(hi-lo) + 1) / (float64(last-first) + 1)
+}
+
+// seqEstimate estimates the fraction of inodes with a meta_seq or
+// data_seq from lo to hi.  Sequence numbers are at most one past the
+// last committed sequence.
+func seqEstimate(f *os.File, lo, hi uint64) (float64, error) {
+	id, err := GetIDs(f)
+	if err != nil {
+		return 0, err
+	}
+	last := id.CommittedSeq
+	if last < max64 {
+		last++
+	}
+	return rangeFraction(lo, hi, 0, last), nil
+}
+
+// indexEstimate estimates the fraction of the entries of index major
+// with minors from lo to hi from the smallest and largest minors
+func indexEstimate(f *os.File, major uint8, lo, hi uint64) (float64, error) {
+	first, err := NewIndexSearch(f, major, 0, max64, WithIBatchSize(1)).Next()
+	if err != nil {
+		return 0, fmt.Errorf("read index: %v", err)
+	}
+	if len(first) == 0 {
+		return 0, nil
+	}
+	last, err := IndexTopN(f, major, 0, max64, 1)
+	if err != nil {
+		return 0, fmt.Errorf("read index: %v", err)
+	}
+	if len(last) == 0 {
+		return 0, nil
+	}
+	return rangeFraction(lo, hi, first[0].Value, last[0].Value), nil
+}
+
+func flattenAnd(e policyExpr, conds *[]policyExpr) {
+	if b, ok := e.(*policyBinary); ok && b.op == "and" {
+		flattenAnd(b.l, conds)
+		flattenAnd(b.r, conds)
+		return
+	}
+	*conds = append(*conds, e)
+}
+
+// compareRange returns the range of unsigned values satisfying "x op v",
+// false if the condition isn't a range
+func compareRange(op string, v policyValue) (uint64, uint64, bool) {
+	if v.neg {
+		switch op {
+		case ">", ">=", "!=":
+			return 0, max64, true
+		}
+		return 1, 0, true
+	}
+	u := v.n
+	switch op {
+	case "==":
+		return u, u, true
+	case ">":
+		if u == max64 {
+			return 1, 0, true
+		}
+		return u + 1, max64, true
+	case ">=":
+		return u, max64, true
+	case "<":
+		if u == 0 {
+			return 1, 0, true
+		}
+		return 0, u - 1, true
+	case "<=":
+		return 0, u, true
+	}
+	return 0, 0, false
+}
+
+func planCondition(f *os.File, c policyExpr) (PolicyPlan, bool, error) {
+	if call, ok := c.(*policyCall); ok && call.name == "xattr" {
+		x, err := ParseXattrName(call.xattr)
+		if err != nil || !x.Search {
+			return PolicyPlan{}, false, nil
+		}
+		return PolicyPlan{
+			Source:   PolicyXattrSearch,
+			Xattr:    call.xattr,
+			Estimate: xattrSearchEstimate,
+			Reason:   "matches require the search xattr",
+		}, true, nil
+	}
+
+	b, ok := c.(*policyBinary)
+	if !ok {
+		return PolicyPlan{}, false, nil
+	}
+	lit, ok := b.r.(*policyLit)
+	if !ok || lit.v.kind != kindNumber {
+		return PolicyPlan{}, false, nil
+	}
+	lo, hi, ok := compareRange(b.op, lit.v)
+	if !ok {
+		return PolicyPlan{}, false, nil
+	}
+	if lo > hi {
+		return PolicyPlan{Source: PolicyNone, Reason: "empty range"}, true, nil
+	}
+
+	switch l := b.l.(type) {
+	case *policyCall:
+		switch l.name {
+		case "index":
+			est, err := indexEstimate(f, l.major, lo, hi)
+			if err != nil {
+				return PolicyPlan{}, false, err
+			}
+			return PolicyPlan{
+				Source:     PolicyIndexRange,
+				IndexMajor: l.major,
+				IndexStart: lo,
+				IndexEnd:   hi,
+				Estimate:   est,
+				Reason: fmt.Sprintf("matches require an index minor in range, %.2g%% of the index",
+					est*100),
+			}, true, nil
+		case "totl":
+			if lo == 0 {
+				return PolicyPlan{}, false, nil
+			}
+			t, err := ReadXattrTotals(f, l.totl[0], l.totl[1], l.totl[2])
+			if err != nil {
+				return PolicyPlan{}, false, fmt.Errorf("read totals: %v", err)
+			}
+			if t.Count == 0 {
+				return PolicyPlan{
+					Source: PolicyNone,
+					Reason: fmt.Sprintf("no inodes have totl %v.%v.%v",
+						l.totl[0], l.totl[1], l.totl[2]),
+				}, true, nil
+			}
+		}
+	case *policyField:
+		switch l.name {
+		case "meta_seq", "data_seq":
+			est, err := seqEstimate(f, lo, hi)
+			if err != nil {
+				return PolicyPlan{}, false, err
+			}
+			return PolicyPlan{
+				Source:   PolicySeqWalk,
+				DataSeq:  l.name == "data_seq",
+				SeqStart: lo,
+				SeqEnd:   hi,
+				Estimate: est,
+				Reason: fmt.Sprintf("matches require %v in range, %.2g%% of the sequence numbers",
+					l.name, est*100),
+			}, true, nil
+		}
+	}
+	return PolicyPlan{}, false, nil
+}
+
+// PolicyMatches streams the inodes matching a policy
+type PolicyMatches struct {
+	policy *Policy
+	plan   PolicyPlan
+	f      *os.File
+	src    InodeSource
+	now    time.Time
+	buf    []byte
+}
+
+// Run plans the policy and returns the stream of matching inodes.
+// Conditions on now use the time Run is called.
+// An open file within scoutfs is supplied for ioctls
+// (usually just the base mount point directory)
+func (p *Policy) Run(f *os.File) (*PolicyMatches, error) {
+	plan, err := p.Plan(f)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &PolicyMatches{
+		policy: p,
+		plan:   plan,
+		f:      f,
+		now:    time.Now(),
+	}
+
+	switch plan.Source {
+	case PolicyXattrSearch:
+		m.src = NewXattrQuery(f, plan.Xattr)
+	case PolicyIndexRange:
+		m.src = &indexInodes{
+			s:    NewIndexSearch(f, plan.IndexMajor, plan.IndexStart, plan.IndexEnd),
+			seen: make(map[uint64]struct{}),
+		}
+	case PolicySeqWalk:
+		from := InodesEntry{Major: plan.SeqStart}
+		to := InodesEntry{Major: plan.SeqEnd, Minor: max32, Ino: max64}
+		by := ByMSeq(from, to)
+		if plan.DataSeq {
+			by = ByDSeq(from, to)
+		}
+		m.src = &seqInodes{q: NewQuery(f, by, WithBatchSize(1024))}
+	}
+
+	return m, nil
+}
+
+// Plan returns the plan used for the matches
+func (m *PolicyMatches) Plan() PolicyPlan {
+	return m.plan
+}
+
+// Next gets the next batch of matching inodes, returns nil when complete.
+// Inodes removed while being evaluated are skipped.
+func (m *PolicyMatches) Next() ([]uint64, error) {
+	if m.src == nil {
+		return nil, nil
+	}
+
+	for {
+		inodes, err := m.src.Next()
+		if err != nil {
+			return nil, err
+		}
+		if len(inodes) == 0 {
+			return nil, nil
+		}
+
+		var matched []uint64
+		for _, ino := range inodes {
+			ok, err := m.match(ino)
+			if err != nil {
+				return nil, fmt.Errorf("evaluate inode %v: %v", ino, err)
+			}
+			if ok {
+				matched = append(matched, ino)
+			}
+		}
+		if len(matched) > 0 {
+			return matched, nil
+		}
+	}
+}
+
+func (m *PolicyMatches) match(ino uint64) (bool, error) {
+	in := &policyInode{dirfd: m.f, ino: ino, now: m.now, buf: m.buf}
+	defer in.close()
+
+	v, err := m.policy.root.eval(in)
+	if m.buf == nil {
+		m.buf = in.buf
+	}
+	if err == syscall.ENOENT || err == syscall.ESTALE {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return v.b, nil
+}
+
+// seqInodes returns the inode numbers from a Query
+type seqInodes struct {
+	q *Query
+}
+
+func (s *seqInodes) Next() ([]uint64, error) {
+	ents, err := s.q.Next()
+	if err != nil || len(ents) == 0 {
+		return nil, err
+	}
+	inodes := make([]uint64, len(ents))
+	for i, e := range ents {
+		inodes[i] = e.Ino
+	}
+	return inodes, nil
+}
+
+// indexInodes returns each inode from an IndexSearch once
+type indexInodes struct {
+	s    *IndexSearch
+	seen map[uint64]struct{}
+}
+
+func (s *indexInodes) Next() ([]uint64, error) {
+	for {
+		ents, err := s.s.Next()
+		if err != nil || ents == nil {
+			return nil, err
+		}
+		var inodes []uint64
+		for _, e := range ents {
+			if _, ok := s.seen[e.Inode]; !ok {
+				s.seen[e.Inode] = struct{}{}
+				inodes = append(inodes, e.Inode)
+			}
+		}
+		if len(inodes) > 0 {
+			return inodes, nil
+		}
+	}
+}
+
+// policyInode loads the attributes of an inode as the policy needs them
+type policyInode struct {
+	dirfd *os.File
+	ino   uint64
+	now   time.Time
+	buf   []byte
+
+	opened bool
+	f      *os.File
+	st     *syscall.Stat_t
+	more   *Stat
+	proj   *uint64
+	xattrs []XattrName
+	listed bool
+}
+
+func (in *policyInode) close() {
+	if in.f != nil {
+		in.f.Close()
+	}
+}
+
+// open opens the inode by handle, inodes that can't be opened, such as
+// symlinks, are stat'd by path and have no scoutfs attributes
+func (in *policyInode) open() error {
+	if in.opened {
+		return nil
+	}
+	in.opened = true
+
+	fd, err := OpenByHandle(in.dirfd, in.ino,
+		syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_NOFOLLOW)
+	if err == nil {
+		in.f = os.NewFile(fd, "")
+		var st syscall.Stat_t
+		err = syscall.Fstat(int(fd), &st)
+		if err != nil {
+			return err
+		}
+		in.st = &st
+		return nil
+	}
+	if err != syscall.ELOOP && err != syscall.ENXIO {
+		return err
+	}
+
+	path, err := InoToPath(in.dirfd, in.ino)
+	if err != nil {
+		return err
+	}
+	var st syscall.Stat_t
+	err = syscall.Lstat(filepath.Join(in.dirfd.Name(), path), &st)
+	if err != nil {
+		return err
+	}
+	in.st = &st
+	return nil
+}
+
+func (in *policyInode) stat() (*syscall.Stat_t, error) {
+	err := in.open()
+	return in.st, err
+}
+
+func (in *policyInode) statMore() (Stat, error) {
+	if in.more != nil {
+		return *in.more, nil
+	}
+	err := in.open()
+	if err != nil || in.f == nil {
+		return Stat{}, err
+	}
+	s, err := FStatMore(in.f)
+	if err != nil {
+		return Stat{}, err
+	}
+	in.more = &s
+	return s, nil
+}
+
+func (in *policyInode) project() (uint64, error) {
+	if in.proj != nil {
+		return *in.proj, nil
+	}
+	err := in.open()
+	if err != nil || in.f == nil {
+		return 0, err
+	}
+	id, err := GetProjectID(in.f)
+	if err != nil {
+		return 0, err
+	}
+	in.proj = &id
+	return id, nil
+}
+
+// xattrNames returns the inode's scoutfs xattrs
+func (in *policyInode) xattrNames() ([]XattrName, error) {
+	if in.listed {
+		return in.xattrs, nil
+	}
+	err := in.open()
+	if err != nil || in.f == nil {
+		return nil, err
+	}
+	if in.buf == nil {
+		in.buf = make([]byte, listattrBufsize)
+	}
+
+	lxr := NewListXattrHidden(in.f, in.buf)
+	for {
+		names, err := lxr.Next()
+		if err != nil {
+			return nil, err
+		}
+		if names == nil {
+			break
+		}
+		for _, name := range names {
+			x, err := ParseXattrName(name)
+			if err == nil {
+				in.xattrs = append(in.xattrs, x)
+			}
+		}
+	}
+	in.listed = true
+	return in.xattrs, nil
+}
+
+func (e *policyLit) eval(in *policyInode) (policyValue, error) {
+	return e.v, nil
+}
+
+func (e *policyNow) eval(in *policyInode) (policyValue, error) {
+	return policyValue{kind: kindTime, t: in.now}, nil
+}
+
+func timespecTime(ts syscall.Timespec) time.Time {
+	return time.Unix(ts.Unix())
+}
+
+var policyTypes = map[uint32]string{
+	syscall.S_IFREG:  "file",
+	syscall.S_IFDIR:  "dir",
+	syscall.S_IFLNK:  "symlink",
+	syscall.S_IFIFO:  "fifo",
+	syscall.S_IFSOCK: "socket",
+	syscall.S_IFCHR:  "char",
+	syscall.S_IFBLK:  "block",
+}
+
+func (e *policyField) eval(in *policyInode) (policyValue, error) {
+	num := func(n uint64) (policyValue, error) {
+		return numberValue(n), nil
+	}
+
+	switch e.name {
+	case "ino":
+		return num(in.ino)
+	case "project":
+		id, err := in.project()
+		if err != nil {
+			return policyValue{}, err
+		}
+		return num(id)
+	case "meta_seq", "data_seq", "data_version", "online_blocks",
+		"offline_blocks", "crtime":
+		s, err := in.statMore()
+		if err != nil {
+			return policyValue{}, err
+		}
+		switch e.name {
+		case "meta_seq":
+			return num(s.Meta_seq)
+		case "data_seq":
+			return num(s.Data_seq)
+		case "data_version":
+			return num(s.Data_version)
+		case "online_blocks":
+			return num(s.Online_blocks)
+		case "offline_blocks":
+			return num(s.Offline_blocks)
+		}
+		return policyValue{kind: kindTime,
+			t: time.Unix(int64(s.Crtime_sec), int64(s.Crtime_nsec))}, nil
+	}
+
+	st, err := in.stat()
+	if err != nil {
+		return policyValue{}, err
+	}
+	switch e.name {
+	case "size":
+		return num(uint64(st.Size))
+	case "uid":
+		return num(uint64(st.Uid))
+	case "gid":
+		return num(uint64(st.Gid))
+	case "nlink":
+		return num(uint64(st.Nlink))
+	case "mode":
+		return num(uint64(st.Mode & 07777))
+	case "type":
+		return policyValue{kind: kindString, s: policyTypes[st.Mode&syscall.S_IFMT]}, nil
+	case "atime":
+		return policyValue{kind: kindTime, t: timespecTime(st.Atim)}, nil
+	case "mtime":
+		return policyValue{kind: kindTime, t: timespecTime(st.Mtim)}, nil
+	case "ctime":
+		return policyValue{kind: kindTime, t: timespecTime(st.Ctim)}, nil
+	}
+	return policyValue{}, fmt.Errorf("unknown field %q", e.name)
+}
+
+// indexMinors returns the inode's minors in the call's index major
+func (e *policyCall) indexMinors(in *policyInode) ([]uint64, error) {
+	xattrs, err := in.xattrNames()
+	if err != nil {
+		return nil, err
+	}
+	var minors []uint64
+	for _, x := range xattrs {
+		if x.Index && x.IndexMajor == e.major {
+			minors = append(minors, x.IndexMinor)
+		}
+	}
+	return minors, nil
+}
+
+func (e *policyCall) eval(in *policyInode) (policyValue, error) {
+	switch e.name {
+	case "xattr":
+		if !IsScoutfsXattr(e.xattr) {
+			err := in.open()
+			if err != nil || in.f == nil {
+				return policyValue{kind: kindBool}, err
+			}
+			_, err = fgetxattr(in.f, e.xattr, nil)
+			if err == syscall.ENODATA {
+				return policyValue{kind: kindBool}, nil
+			}
+			return policyValue{kind: kindBool, b: err == nil}, err
+		}
+		xattrs, err := in.xattrNames()
+		if err != nil {
+			return policyValue{}, err
+		}
+		for _, x := range xattrs {
+			if x.String() == e.xattr {
+				return policyValue{kind: kindBool, b: true}, nil
+			}
+		}
+		return policyValue{kind: kindBool}, nil
+
+	case "index":
+		minors, err := e.indexMinors(in)
+		if err != nil || len(minors) == 0 {
+			return policyValue{kind: kindNumber}, err
+		}
+		return numberValue(minors[0]), nil
+
+	case "totl":
+		xattrs, err := in.xattrNames()
+		if err != nil {
+			return policyValue{}, err
+		}
+		for _, x := range xattrs {
+			if !x.Totl || x.TotlID != e.totl {
+				continue
+			}
+			b, err := GetXattr(in.f, x.String(), in.buf)
+			if err != nil {
+				return policyValue{}, err
+			}
+			v, err := strconv.ParseUint(string(b), 10, 64)
+			if err != nil {
+				return policyValue{}, fmt.Errorf("totl xattr %q: %v", x.String(), err)
+			}
+			return numberValue(v), nil
+		}
+		return policyValue{kind: kindNumber}, nil
+	}
+	return policyValue{}, fmt.Errorf("unknown function %q", e.name)
+}
+
+func (e *policyNot) eval(in *policyInode) (policyValue, error) {
+	v, err := e.x.eval(in)
+	return policyValue{kind: kindBool, b: !v.b}, err
+}
+
+func (e *policyBinary) eval(in *policyInode) (policyValue, error) {
+	switch e.op {
+	case "and", "or":
+		l, err := e.l.eval(in)
+		if err != nil {
+			return policyValue{}, err
+		}
+		if (e.op == "and") != l.b {
+			return l, nil
+		}
+		return e.r.eval(in)
+	}
+
+	r, err := e.r.eval(in)
+	if err != nil {
+		return policyValue{}, err
+	}
+
+	if c, ok := e.l.(*policyCall); ok && c.name == "index" {
+		minors, err := c.indexMinors(in)
+		if err != nil {
+			return policyValue{}, err
+		}
+		for _, m := range minors {
+			l := numberValue(m)
+			if compareValues(e.op, l, r) {
+				return policyValue{kind: kindBool, b: true}, nil
+			}
+		}
+		return policyValue{kind: kindBool}, nil
+	}
+
+	l, err := e.l.eval(in)
+	if err != nil {
+		return policyValue{}, err
+	}
+
+	switch e.op {
+	case "+", "-":
+		sign := int64(1)
+		if e.op == "-" {
+			sign = -1
+		}
+		switch l.kind {
+		case kindNumber:
+			return addNumbers(l, r, e.op == "-"), nil
+		case kindDuration:
+			return policyValue{kind: kindDuration, d: l.d + time.Duration(sign)*r.d}, nil
+		}
+		return policyValue{kind: kindTime, t: l.t.Add(time.Duration(sign) * r.d)}, nil
+	}
+
+	return policyValue{kind: kindBool, b: compareValues(e.op, l, r)}, nil
+}
+
+func compareValues(op string, l, r policyValue) bool {
+	var c int
+	switch l.kind {
+	case kindNumber:
+		c = cmpNumbers(l, r)
+	case kindDuration:
+		c = cmpInt64(int64(l.d), int64(r.d))
+	case kindTime:
+		c = cmpInt64(l.t.UnixNano(), r.t.UnixNano())
+	case kindString:
+		if l.s != r.s {
+			c = 1
+		}
+	case kindBool:
+		if l.b != r.b {
+			c = 1
+		}
+	}
+
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+func cmpInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,303 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// ProjectTree manages project IDs for a directory tree
+type ProjectTree struct {
+	root    string
+	workers int
+}
+
+// PTOption sets various options for NewProjectTree
+type PTOption func(*ProjectTree)
+
+// WithProjectWorkers sets the number of parallel directory walkers
+func WithProjectWorkers(n int) PTOption {
+	return func(p *ProjectTree) {
+		p.workers = n
+	}
+}
+
+// NewProjectTree creates a ProjectTree for the directory tree at root
+func NewProjectTree(root string, opts ...PTOption) *ProjectTree {
+	p := &ProjectTree{
+		root:    root,
+		workers: 8,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Set sets the project ID on root and all directories and regular files
+// below it, returning the number of inodes updated
+func (p *ProjectTree) Set(projectid uint64) (uint64, error) {
+	var mu sync.Mutex
+	var count uint64
+	err := walkParallel(p.root, p.workers,
+		func(path string, f *os.File, isDir bool, parent interface{}) (interface{}, error) {
+			err := SetProjectID(f, projectid)
+			if err != nil {
+				return nil, fmt.Errorf("set project id %q: %v", path, err)
+			}
+			mu.Lock()
+			count++
+			mu.Unlock()
+			return nil, nil
+		})
+	return count, err
+}
+
+// ProjectMismatch is an inode with a project ID that differs from its
+// parent directory
+type ProjectMismatch struct {
+	Path      string
+	ProjectID uint64
+	ParentID  uint64
+}
+
+// Mismatches returns all inodes below root with a project ID that differs
+// from their parent directory
+func (p *ProjectTree) Mismatches() ([]ProjectMismatch, error) {
+	var mu sync.Mutex
+	var mismatches []ProjectMismatch
+	err := walkParallel(p.root, p.workers,
+		func(path string, f *os.File, isDir bool, parent interface{}) (interface{}, error) {
+			id, err := GetProjectID(f)
+			if err != nil {
+				return nil, fmt.Errorf("get project id %q: %v", path, err)
+			}
+			if pid, ok := parent.(uint64); ok && pid != id {
+				mu.Lock()
+				mismatches = append(mismatches, ProjectMismatch{
+					Path:      path,
+					ProjectID: id,
+					ParentID:  pid,
+				})
+				mu.Unlock()
+			}
+			return id, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(mismatches, func(i, j int) bool {
+		return mismatches[i].Path < mismatches[j].Path
+	})
+	return mismatches, nil
+}
+
+// ProjectUsage is the usage accounted to a project ID
+type ProjectUsage struct {
+	ProjectID     uint64
+	Files         uint64
+	Dirs          uint64
+	OnlineBlocks  uint64
+	OfflineBlocks uint64
+}
+
+// OnlineBytes returns the bytes of online data
+func (u ProjectUsage) OnlineBytes() uint64 {
+	return u.OnlineBlocks * scoutfsBS
+}
+
+// OfflineBytes returns the bytes of offline data
+func (u ProjectUsage) OfflineBytes() uint64 {
+	return u.OfflineBlocks * scoutfsBS
+}
+
+// Usage returns the usage below root for each project ID, sorted by
+// project ID.  Files with multiple links below root are counted once.
+func (p *ProjectTree) Usage() ([]ProjectUsage, error) {
+	var mu sync.Mutex
+	usage := make(map[uint64]*ProjectUsage)
+	linked := make(map[uint64]struct{})
+	err := walkParallel(p.root, p.workers,
+		func(path string, f *os.File, isDir bool, parent interface{}) (interface{}, error) {
+			id, err := GetProjectID(f)
+			if err != nil {
+				return nil, fmt.Errorf("get project id %q: %v", path, err)
+			}
+			st, err := FStatMore(f)
+			if err != nil {
+				return nil, fmt.Errorf("stat more %q: %v", path, err)
+			}
+			var ino uint64
+			if !isDir {
+				fi, err := f.Stat()
+				if err != nil {
+					return nil, fmt.Errorf("stat %q: %v", path, err)
+				}
+				if sst, ok := fi.Sys().(*syscall.Stat_t); ok && sst.Nlink > 1 {
+					ino = sst.Ino
+				}
+			}
+
+			mu.Lock()
+			if ino != 0 {
+				if _, ok := linked[ino]; ok {
+					mu.Unlock()
+					return nil, nil
+				}
+				linked[ino] = struct{}{}
+			}
+			u, ok := usage[id]
+			if !ok {
+				u = &ProjectUsage{ProjectID: id}
+				usage[id] = u
+			}
+			if isDir {
+				u.Dirs++
+			} else {
+				u.Files++
+			}
+			u.OnlineBlocks += st.Online_blocks
+			u.OfflineBlocks += st.Offline_blocks
+			mu.Unlock()
+			return nil, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]ProjectUsage, 0, len(usage))
+	for _, u := range usage {
+		ret = append(ret, *u)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].ProjectID < ret[j].ProjectID
+	})
+	return ret, nil
+}
+
+// walkFunc is called with each open directory and regular file and the
+// value returned for its parent directory.  The value returned for a
+// directory is passed to its children.
+type walkFunc func(path string, f *os.File, isDir bool, parent interface{}) (interface{}, error)
+
+type walkJob struct {
+	path   string
+	parent interface{}
+}
+
+// walkParallel walks the tree at root with the given number of workers
+// reading directories.  The first error stops the walk.
+func walkParallel(root string, workers int, fn walkFunc) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	f, err := os.Open(root)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	val, err := fn(root, f, fi.IsDir(), nil)
+	f.Close()
+	if err != nil || !fi.IsDir() {
+		return err
+	}
+
+	var (
+		mu       sync.Mutex
+		cond     = sync.NewCond(&mu)
+		queue    = []walkJob{{path: root, parent: val}}
+		active   int
+		firstErr error
+		wg       sync.WaitGroup
+	)
+
+	worker := func() {
+		defer wg.Done()
+		for {
+			mu.Lock()
+			for len(queue) == 0 && active > 0 && firstErr == nil {
+				cond.Wait()
+			}
+			if len(queue) == 0 || firstErr != nil {
+				mu.Unlock()
+				cond.Broadcast()
+				return
+			}
+			job := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			active++
+			mu.Unlock()
+
+			children, err := walkDir(job, fn)
+
+			mu.Lock()
+			active--
+			queue = append(queue, children...)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			mu.Unlock()
+			cond.Broadcast()
+		}
+	}
+
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go worker()
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+func walkDir(job walkJob, fn walkFunc) ([]walkJob, error) {
+	ents, err := os.ReadDir(job.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var children []walkJob
+	for _, ent := range ents {
+		typ := ent.Type()
+		if !typ.IsDir() && !typ.IsRegular() {
+			continue
+		}
+
+		path := filepath.Join(job.path, ent.Name())
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return children, err
+		}
+		val, err := fn(path, f, typ.IsDir(), job.parent)
+		f.Close()
+		if err != nil {
+			return children, err
+		}
+
+		if typ.IsDir() {
+			children = append(children, walkJob{path: path, parent: val})
+		}
+	}
+
+	return children, nil
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"fmt"
+	"math"
+	"os"
+)
+
+// Quota rule name sources
+const (
+	QuotaSourceLiteral QuotaType = quotaLiteral
+	QuotaSourceProject QuotaType = quotaProj
+	QuotaSourceUID     QuotaType = quotaUID
+	QuotaSourceGID     QuotaType = quotaGID
+)
+
+const (
+	// QuotaNameSelect is set in QuotaFlags when the rule only applies to
+	// the name value
+	QuotaNameSelect = quotaSelect
+	// QuotaRuleCount is set in Flags when the rule limits the totl count
+	// rather than the totl total
+	QuotaRuleCount = quotaFlagCount
+	// QuotaPriorityMax is the highest rule priority
+	QuotaPriorityMax = math.MaxUint8
+)
+
+// QuotaRuleBuilder builds and validates a QuotaRule
+type QuotaRuleBuilder struct {
+	rule QuotaRule
+	err  error
+}
+
+// NewQuotaRuleBuilder starts a rule for the op.  Inode rules limit the
+// totl count and data rules limit the totl total.  All names start as
+// literal 0.
+func NewQuotaRuleBuilder(op QuotaOp) *QuotaRuleBuilder {
+	b := &QuotaRuleBuilder{}
+	b.rule.Op = op
+	if op == QuotaInode {
+		b.rule.Flags = quotaFlagCount
+	}
+	return b
+}
+
+func (b *QuotaRuleBuilder) setErr(err error) *QuotaRuleBuilder {
+	if b.err == nil {
+		b.err = err
+	}
+	return b
+}
+
+func (b *QuotaRuleBuilder) name(slot int, source QuotaType, value uint64, flags uint8) *QuotaRuleBuilder {
+	if slot < 0 || slot > 2 {
+		return b.setErr(fmt.Errorf("name slot %v out of range 0-2", slot))
+	}
+	b.rule.QuotaSource[slot] = uint8(source)
+	b.rule.QuotaValue[slot] = value
+	b.rule.QuotaFlags[slot] = flags
+	return b
+}
+
+// Priority sets the rule priority, higher priority rules match first
+func (b *QuotaRuleBuilder) Priority(prio int) *QuotaRuleBuilder {
+	if prio < 0 || prio > QuotaPriorityMax {
+		return b.setErr(fmt.Errorf("priority %v out of range 0-%v",
+			prio, QuotaPriorityMax))
+	}
+	b.rule.Prioirity = uint8(prio)
+	return b
+}
+
+// Literal sets the name slot to a literal value
+func (b *QuotaRuleBuilder) Literal(slot int, value uint64) *QuotaRuleBuilder {
+	return b.name(slot, QuotaSourceLiteral, value, 0)
+}
+
+// Select sets the name slot to only match files with the source value
+func (b *QuotaRuleBuilder) Select(slot int, source QuotaType, value uint64) *QuotaRuleBuilder {
+	return b.name(slot, source, value, quotaSelect)
+}
+
+// General sets the name slot to match any source value, each value is
+// limited separately
+func (b *QuotaRuleBuilder) General(slot int, source QuotaType) *QuotaRuleBuilder {
+	return b.name(slot, source, 0, 0)
+}
+
+// Limit sets the rule limit
+func (b *QuotaRuleBuilder) Limit(limit uint64) *QuotaRuleBuilder {
+	b.rule.Limit = limit
+	return b
+}
+
+// Build returns the validated rule
+func (b *QuotaRuleBuilder) Build() (QuotaRule, error) {
+	if b.err != nil {
+		return QuotaRule{}, b.err
+	}
+	err := ValidateQuotaRule(b.rule)
+	if err != nil {
+		return QuotaRule{}, err
+	}
+	return b.rule, nil
+}
+
+// ValidateQuotaRule checks that the rule is well formed
+func ValidateQuotaRule(q QuotaRule) error {
+	switch q.Op {
+	case QuotaInode:
+		if q.Flags&quotaFlagCount == 0 {
+			return fmt.Errorf("inode rule must have count flag")
+		}
+	case QuotaData:
+		if q.Flags&quotaFlagCount != 0 {
+			return fmt.Errorf("data rule must not have count flag")
+		}
+	default:
+		return fmt.Errorf("unknown op %v", uint8(q.Op))
+	}
+	if q.Flags&^quotaFlagCount != 0 {
+		return fmt.Errorf("unknown rule flags %#x", q.Flags)
+	}
+
+	for i := range q.QuotaSource {
+		if q.QuotaFlags[i]&^quotaSelect != 0 {
+			return fmt.Errorf("name %v: unknown flags %#x", i, q.QuotaFlags[i])
+		}
+		switch q.QuotaSource[i] {
+		case quotaLiteral:
+			if q.QuotaFlags[i]&quotaSelect != 0 {
+				return fmt.Errorf("name %v: literal can not be selected", i)
+			}
+		case quotaProj, quotaUID, quotaGID:
+			if q.QuotaFlags[i]&quotaSelect == 0 && q.QuotaValue[i] != 0 {
+				return fmt.Errorf("name %v: general %v must have value 0",
+					i, QuotaType(q.QuotaSource[i]))
+			}
+		default:
+			return fmt.Errorf("name %v: unknown source %v", i, q.QuotaSource[i])
+		}
+	}
+
+	return nil
+}
+
+// QuotaAdd validates and adds the quota rule
+func QuotaAdd(f *os.File, q QuotaRule) error {
+	err := ValidateQuotaRule(q)
+	if err != nil {
+		return err
+	}
+	return quotaAdd(f, q)
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// QuotaInput holds the attributes of a file that quota rules match on
+type QuotaInput struct {
+	UID       uint64
+	GID       uint64
+	ProjectID uint64
+	// Totls are the literal totl ids the file has xattrs for
+	Totls [][3]uint64
+}
+
+// QuotaInputFor returns the quota attributes of file handle
+func QuotaInputFor(f *os.File) (QuotaInput, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return QuotaInput{}, err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return QuotaInput{}, fmt.Errorf("unsupported stat type %T", fi.Sys())
+	}
+
+	proj, err := GetProjectID(f)
+	if err != nil {
+		return QuotaInput{}, fmt.Errorf("get project id: %v", err)
+	}
+
+	in := QuotaInput{
+		UID:       uint64(st.Uid),
+		GID:       uint64(st.Gid),
+		ProjectID: proj,
+	}
+
+	lxr := NewListXattrHidden(f, nil)
+	for {
+		names, err := lxr.Next()
+		if err != nil {
+			return QuotaInput{}, fmt.Errorf("list xattrs: %v", err)
+		}
+		if names == nil {
+			break
+		}
+		for _, name := range names {
+			x, err := ParseXattrName(name)
+			if err == nil && x.Totl {
+				in.Totls = append(in.Totls, x.TotlID)
+			}
+		}
+	}
+
+	return in, nil
+}
+
+// QuotaTrace records a rule considered while evaluating and why it did or
+// did not match
+type QuotaTrace struct {
+	Rule    QuotaRule
+	Matched bool
+	Reason  string
+}
+
+// QuotaMatch is the rule that applies to a file for an op
+type QuotaMatch struct {
+	Op QuotaOp
+	// Rule is the matching rule, nil if no rule applies
+	Rule *QuotaRule
+	// Totl is the totl id whose usage is checked against the rule limit
+	Totl [3]uint64
+	// Trace holds the rules for the op in match order up to the match
+	Trace []QuotaTrace
+}
+
+// String returns the explanation of how the rule was matched
+func (m QuotaMatch) String() string {
+	var b strings.Builder
+	if m.Rule == nil {
+		fmt.Fprintf(&b, "%v: no matching rule\n", m.Op)
+	} else {
+		fmt.Fprintf(&b, "%v: %v totl %v.%v.%v\n", m.Op,
+			FormatQuotaRule(*m.Rule), m.Totl[0], m.Totl[1], m.Totl[2])
+	}
+	for _, t := range m.Trace {
+		res := "skip"
+		if t.Matched {
+			res = "match"
+		}
+		fmt.Fprintf(&b, "  %-5v %v: %v\n", res, FormatQuotaRule(t.Rule), t.Reason)
+	}
+	return b.String()
+}
+
+// QuotaEvaluation holds the rules that apply to a file for each op
+type QuotaEvaluation struct {
+	Inode QuotaMatch
+	Data  QuotaMatch
+}
+
+// String returns the explanation for both ops
+func (e QuotaEvaluation) String() string {
+	return e.Inode.String() + e.Data.String()
+}
+
+// EvaluateQuota returns the rules that would limit a file with the given
+// attributes.  Rules are considered in the order the filesystem matches
+// them and the first matching rule for each op applies.  Rules with all
+// literal names only match files with a totl xattr for those ids.
+func EvaluateQuota(rules RuleSet, in QuotaInput) QuotaEvaluation {
+	sorted := make(RuleSet, len(rules))
+	copy(sorted, rules)
+	sort.Sort(sorted)
+
+	return QuotaEvaluation{
+		Inode: evaluateQuotaOp(sorted, in, QuotaInode),
+		Data:  evaluateQuotaOp(sorted, in, QuotaData),
+	}
+}
+
+func evaluateQuotaOp(rules RuleSet, in QuotaInput, op QuotaOp) QuotaMatch {
+	m := QuotaMatch{Op: op}
+	for i := range rules {
+		if rules[i].Op != op {
+			continue
+		}
+		totl, reason, ok := matchQuotaRule(rules[i], in)
+		m.Trace = append(m.Trace, QuotaTrace{
+			Rule:    rules[i],
+			Matched: ok,
+			Reason:  reason,
+		})
+		if ok {
+			rule := rules[i]
+			m.Rule = &rule
+			m.Totl = totl
+			break
+		}
+	}
+	return m
+}
+
+func matchQuotaRule(q QuotaRule, in QuotaInput) ([3]uint64, string, bool) {
+	var totl [3]uint64
+	var reasons []string
+	literal := true
+	for i := range q.QuotaValue {
+		var val uint64
+		switch q.QuotaSource[i] {
+		case quotaLiteral:
+			totl[i] = q.QuotaValue[i]
+			continue
+		case quotaProj:
+			val = in.ProjectID
+		case quotaUID:
+			val = in.UID
+		case quotaGID:
+			val = in.GID
+		default:
+			return totl, fmt.Sprintf("unknown source %v", q.QuotaSource[i]), false
+		}
+
+		literal = false
+		src := QuotaType(q.QuotaSource[i])
+		if q.QuotaFlags[i]&quotaSelect != 0 {
+			if val != q.QuotaValue[i] {
+				return totl, fmt.Sprintf("%v %v != %v", src, val, q.QuotaValue[i]), false
+			}
+			reasons = append(reasons, fmt.Sprintf("%v %v selected", src, val))
+		} else {
+			reasons = append(reasons, fmt.Sprintf("%v %v general", src, val))
+		}
+		totl[i] = val
+	}
+
+	if literal {
+		for _, t := range in.Totls {
+			if t == totl {
+				return totl, "file has literal totl", true
+			}
+		}
+		return totl, "file has no literal totl", false
+	}
+
+	return totl, strings.Join(reasons, ", "), true
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"testing"
+)
+
+func TestEvaluateQuota(t *testing.T) {
+	const (
+		ruleLiteral = "40 7,L,- 8,L,- 9,L,- D 1 -"
+		ruleProjSel = "30 0,L,- 0,L,- 7,P,S D 1000 -"
+		ruleGIDSel  = "20 0,L,- 0,L,- 5,G,S I 50 C"
+		ruleUIDSel  = "10 0,L,- 0,L,- 1000,U,S D 100 -"
+		ruleUIDGen  = "1 0,L,- 0,L,- 0,U,- D 10 -"
+	)
+	rules := parseRules(t, ruleUIDGen, ruleUIDSel, ruleGIDSel, ruleProjSel, ruleLiteral)
+
+	tests := []struct {
+		name      string
+		in        QuotaInput
+		data      string
+		dataTotl  [3]uint64
+		dataTrace int
+		inode     string
+		inodeTotl [3]uint64
+	}{
+		{
+			name:      "higher priority project",
+			in:        QuotaInput{UID: 1000, GID: 5, ProjectID: 7},
+			data:      ruleProjSel,
+			dataTotl:  [3]uint64{0, 0, 7},
+			dataTrace: 2,
+			inode:     ruleGIDSel,
+			inodeTotl: [3]uint64{0, 0, 5},
+		},
+		{
+			name:      "selected uid",
+			in:        QuotaInput{UID: 1000, GID: 6, ProjectID: 8},
+			data:      ruleUIDSel,
+			dataTotl:  [3]uint64{0, 0, 1000},
+			dataTrace: 3,
+		},
+		{
+			name:      "general uid",
+			in:        QuotaInput{UID: 2, GID: 6},
+			data:      ruleUIDGen,
+			dataTotl:  [3]uint64{0, 0, 2},
+			dataTrace: 4,
+		},
+		{
+			name: "literal totl",
+			in: QuotaInput{UID: 1000, ProjectID: 7,
+				Totls: [][3]uint64{{1, 2, 3}, {7, 8, 9}}},
+			data:      ruleLiteral,
+			dataTotl:  [3]uint64{7, 8, 9},
+			dataTrace: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := EvaluateQuota(rules, tt.in)
+
+			check := func(m QuotaMatch, op QuotaOp, want string, totl [3]uint64) {
+				t.Helper()
+				if m.Op != op {
+					t.Errorf("op = %v, want %v", m.Op, op)
+				}
+				if want == "" {
+					if m.Rule != nil {
+						t.Errorf("%v matched %q, want no rule", op, FormatQuotaRule(*m.Rule))
+					}
+					return
+				}
+				if m.Rule == nil {
+					t.Fatalf("%v matched no rule, want %q", op, want)
+				}
+				if got := FormatQuotaRule(*m.Rule); got != want {
+					t.Errorf("%v matched %q, want %q", op, got, want)
+				}
+				if m.Totl != totl {
+					t.Errorf("%v totl = %v, want %v", op, m.Totl, totl)
+				}
+				last := m.Trace[len(m.Trace)-1]
+				if !last.Matched || FormatQuotaRule(last.Rule) != want {
+					t.Errorf("%v trace ends with %+v", op, last)
+				}
+				for _, tr := range m.Trace[:len(m.Trace)-1] {
+					if tr.Matched {
+						t.Errorf("%v trace matched before the rule: %+v", op, tr)
+					}
+				}
+			}
+			check(e.Data, QuotaData, tt.data, tt.dataTotl)
+			check(e.Inode, QuotaInode, tt.inode, tt.inodeTotl)
+			if len(e.Data.Trace) != tt.dataTrace {
+				t.Errorf("data trace has %v rules, want %v", len(e.Data.Trace), tt.dataTrace)
+			}
+		})
+	}
+}
+
+func TestEvaluateQuotaNoRules(t *testing.T) {
+	e := EvaluateQuota(nil, QuotaInput{UID: 1})
+	if e.Data.Rule != nil || e.Inode.Rule != nil {
+		t.Errorf("EvaluateQuota() with no rules matched %+v", e)
+	}
+	if e.String() == "" {
+		t.Error("String() is empty")
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// quotaExportVersion is the version of the quota export schema
+const quotaExportVersion = 1
+
+// QuotaExport is the JSON schema for exported quota rules
+type QuotaExport struct {
+	Version  int             `json:"version"`
+	Checksum string          `json:"checksum"`
+	Rules    []QuotaRuleJSON `json:"rules"`
+}
+
+// QuotaRuleJSON is the JSON schema for a single quota rule.  The raw
+// fields are used to restore the rule, the human fields are for review
+// and must be consistent with the raw fields.
+type QuotaRuleJSON struct {
+	Priority  uint8     `json:"priority"`
+	Op        uint8     `json:"op"`
+	Values    [3]uint64 `json:"values"`
+	Sources   [3]uint8  `json:"sources"`
+	NameFlags [3]uint8  `json:"name_flags"`
+	Limit     uint64    `json:"limit"`
+	Flags     uint8     `json:"flags"`
+
+	Rule       string  `json:"rule"`
+	Type       string  `json:"type"`
+	ID         *uint64 `json:"id,omitempty"`
+	General    bool    `json:"general"`
+	OpName     string  `json:"op_name"`
+	LimitHuman string  `json:"limit_human"`
+}
+
+func quotaRuleToJSON(q QuotaRule) QuotaRuleJSON {
+	j := QuotaRuleJSON{
+		Priority:   q.Prioirity,
+		Op:         uint8(q.Op),
+		Values:     q.QuotaValue,
+		Sources:    q.QuotaSource,
+		NameFlags:  q.QuotaFlags,
+		Limit:      q.Limit,
+		Flags:      q.Flags,
+		Rule:       FormatQuotaRule(q),
+		Type:       QuotaType(q.QuotaSource[2]).String(),
+		General:    q.IsGeneral(),
+		OpName:     q.Op.String(),
+		LimitHuman: fmt.Sprintf("%v", q.Limit),
+	}
+	if q.QuotaSource[2] != quotaLiteral && !q.IsGeneral() {
+		id := q.QuotaValue[2]
+		j.ID = &id
+	}
+	if q.Op == QuotaData {
+		j.LimitHuman = byteToHuman(q.Limit)
+	}
+	return j
+}
+
+func (j QuotaRuleJSON) rule() (QuotaRule, error) {
+	q := QuotaRule{
+		Op:          QuotaOp(j.Op),
+		QuotaValue:  j.Values,
+		QuotaSource: j.Sources,
+		QuotaFlags:  j.NameFlags,
+		Limit:       j.Limit,
+		Prioirity:   j.Priority,
+		Flags:       j.Flags,
+	}
+
+	err := ValidateQuotaRule(q)
+	if err != nil {
+		return QuotaRule{}, fmt.Errorf("rule %q: %v", j.Rule, err)
+	}
+	if j.Rule != "" {
+		t, err := ParseQuotaRule(j.Rule)
+		if err != nil {
+			return QuotaRule{}, err
+		}
+		if t != q {
+			return QuotaRule{}, fmt.Errorf("rule %q does not match raw fields %q",
+				j.Rule, FormatQuotaRule(q))
+		}
+	}
+	return q, nil
+}
+
+// RuleSetChecksum returns a checksum of the rules that is independent of
+// rule order, two filesystems with the same checksum have identical rules
+func RuleSetChecksum(rules RuleSet) string {
+	sorted := make(RuleSet, len(rules))
+	copy(sorted, rules)
+	sort.Sort(sorted)
+
+	h := sha256.New()
+	for _, q := range sorted {
+		io.WriteString(h, FormatQuotaRule(q))
+		io.WriteString(h, "\n")
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// MarshalRuleSet encodes the rules in the quota export JSON schema
+func MarshalRuleSet(rules RuleSet) ([]byte, error) {
+	sorted := make(RuleSet, len(rules))
+	copy(sorted, rules)
+	sort.Sort(sorted)
+
+	e := QuotaExport{
+		Version:  quotaExportVersion,
+		Checksum: RuleSetChecksum(sorted),
+		Rules:    make([]QuotaRuleJSON, len(sorted)),
+	}
+	for i, q := range sorted {
+		e.Rules[i] = quotaRuleToJSON(q)
+	}
+	return json.MarshalIndent(e, "", "  ")
+}
+
+// UnmarshalRuleSet decodes and validates rules in the quota export JSON
+// schema, including the rule set checksum
+func UnmarshalRuleSet(b []byte) (RuleSet, error) {
+	var e QuotaExport
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return nil, fmt.Errorf("parse quota export: %v", err)
+	}
+	if e.Version != quotaExportVersion {
+		return nil, fmt.Errorf("unsupported quota export version %v", e.Version)
+	}
+
+	rules := make(RuleSet, len(e.Rules))
+	for i, j := range e.Rules {
+		rules[i], err = j.rule()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sum := RuleSetChecksum(rules)
+	if sum != e.Checksum {
+		return nil, fmt.Errorf("checksum mismatch: %v != %v", sum, e.Checksum)
+	}
+
+	return rules, nil
+}
+
+// quotaChecksumComment starts the comment line with the rule set checksum
+// in quota rule text
+const quotaChecksumComment = "# checksum "
+
+// WriteQuotaRules writes the rules one per line in the scoutfs quota rule
+// text syntax, preceded by a comment with the rule set checksum.  The
+// output can be read with ParseQuotaRules, which verifies the checksum.
+func WriteQuotaRules(w io.Writer, rules RuleSet) error {
+	sorted := make(RuleSet, len(rules))
+	copy(sorted, rules)
+	sort.Sort(sorted)
+
+	_, err := fmt.Fprintf(w, "%v%v\n", quotaChecksumComment, RuleSetChecksum(sorted))
+	if err != nil {
+		return err
+	}
+	for _, q := range sorted {
+		_, err = fmt.Fprintln(w, FormatQuotaRule(q))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportQuotaRules writes the current quota rules in the quota export JSON
+// schema
+func ExportQuotaRules(f *os.File, w io.Writer) error {
+	rules, err := ReadQuotaRules(f)
+	if err != nil {
+		return err
+	}
+
+	b, err := MarshalRuleSet(rules)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// readRuleSet reads rules in the quota export JSON schema or the quota
+// rule text syntax
+func readRuleSet(r io.Reader) (RuleSet, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if t := bytes.TrimSpace(b); len(t) > 0 && t[0] == '{' {
+		return UnmarshalRuleSet(b)
+	}
+	return ParseQuotaRules(bytes.NewReader(b))
+}
+
+// RestoreQuotaRules validates rules in the quota export JSON schema, or
+// in the text syntax written by WriteQuotaRules, and reconciles the
+// filesystem rules to match them.  The rule set checksum is verified
+// when present.
+func RestoreQuotaRules(f *os.File, r io.Reader, dryRun bool) (QuotaPlan, error) {
+	rules, err := readRuleSet(r)
+	if err != nil {
+		return QuotaPlan{}, err
+	}
+
+	return Reconcile(f, rules, dryRun)
+}
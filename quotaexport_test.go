@@ -0,0 +1,174 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func exportRules(t *testing.T) RuleSet {
+	t.Helper()
+	return parseRules(t,
+		"1 0,L,- 0,L,- 0,U,- D 10 -",
+		"10 0,L,- 0,L,- 1000,U,S D 100 -",
+		"20 0,L,- 0,L,- 5,G,S I 50 C",
+		"40 7,L,- 8,L,- 9,L,- D 1 -")
+}
+
+func TestRuleSetChecksum(t *testing.T) {
+	rules := exportRules(t)
+	sum := RuleSetChecksum(rules)
+	if !strings.HasPrefix(sum, "sha256:") {
+		t.Errorf("RuleSetChecksum() = %q, want sha256: prefix", sum)
+	}
+
+	reversed := make(RuleSet, len(rules))
+	for i, q := range rules {
+		reversed[len(rules)-1-i] = q
+	}
+	if got := RuleSetChecksum(reversed); got != sum {
+		t.Errorf("checksum depends on order: %v != %v", got, sum)
+	}
+
+	changed := make(RuleSet, len(rules))
+	copy(changed, rules)
+	changed[0].Limit++
+	if got := RuleSetChecksum(changed); got == sum {
+		t.Error("checksum unchanged after changing a limit")
+	}
+	if got := RuleSetChecksum(rules[1:]); got == sum {
+		t.Error("checksum unchanged after removing a rule")
+	}
+}
+
+func TestUnmarshalRuleSet(t *testing.T) {
+	rules := exportRules(t)
+	b, err := MarshalRuleSet(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// edit returns the export after modifying the decoded schema
+	edit := func(fn func(e *QuotaExport)) []byte {
+		var e QuotaExport
+		err := json.Unmarshal(b, &e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fn(&e)
+		out, err := json.Marshal(&e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		b       []byte
+		wantErr bool
+	}{
+		{"round trip", b, false},
+		{"reordered", edit(func(e *QuotaExport) {
+			e.Rules[0], e.Rules[1] = e.Rules[1], e.Rules[0]
+		}), false},
+		{"no rule text", edit(func(e *QuotaExport) {
+			for i := range e.Rules {
+				e.Rules[i].Rule = ""
+			}
+		}), false},
+		{"changed limit", edit(func(e *QuotaExport) {
+			e.Rules[0].Limit++
+			e.Rules[0].Rule = ""
+		}), true},
+		{"changed limit and text", edit(func(e *QuotaExport) {
+			e.Rules[0].Limit++
+			q, _ := e.Rules[0].rule()
+			e.Rules[0].Rule = FormatQuotaRule(q)
+		}), true},
+		{"text disagrees with raw fields", edit(func(e *QuotaExport) {
+			e.Rules[0].Rule = e.Rules[1].Rule
+		}), true},
+		{"removed rule", edit(func(e *QuotaExport) {
+			e.Rules = e.Rules[1:]
+		}), true},
+		{"wrong checksum", edit(func(e *QuotaExport) {
+			e.Checksum = "sha256:00"
+		}), true},
+		{"invalid rule", edit(func(e *QuotaExport) {
+			e.Rules[0].Op = 9
+			e.Rules[0].Rule = ""
+		}), true},
+		{"unsupported version", edit(func(e *QuotaExport) {
+			e.Version = quotaExportVersion + 1
+		}), true},
+		{"not json", []byte("{"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnmarshalRuleSet(tt.b)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalRuleSet() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			want := make(RuleSet, len(rules))
+			copy(want, rules)
+			sort.Sort(want)
+			sort.Sort(got)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("UnmarshalRuleSet() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestReadRuleSet(t *testing.T) {
+	rules := exportRules(t)
+	sum := RuleSetChecksum(rules)
+
+	exp, err := MarshalRuleSet(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text bytes.Buffer
+	err = WriteQuotaRules(&text, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		s       string
+		wantErr bool
+	}{
+		{"json", string(exp), false},
+		{"indented json", "\n  " + string(exp), false},
+		{"text", text.String(), false},
+		{"tampered json", strings.Replace(string(exp), `"limit": 100,`, `"limit": 101,`, 1), true},
+		{"tampered text", strings.Replace(text.String(), " D 100 ", " D 101 ", 1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readRuleSet(strings.NewReader(tt.s))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readRuleSet() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && RuleSetChecksum(got) != sum {
+				t.Errorf("readRuleSet() = %v, want %v", got, rules)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"unsafe"
+)
+
+// totalsBatch is the number of totals read per ioctl for reports
+const totalsBatch = 1024
+
+// QuotaUsage is the usage of a totl id limited by a quota rule
+type QuotaUsage struct {
+	Rule QuotaRule
+	// ID is the totl id the usage was read from
+	ID [3]uint64
+	// Usage is the totl count for rules with the count flag, otherwise
+	// the totl total
+	Usage uint64
+}
+
+// Limit returns the rule limit
+func (u QuotaUsage) Limit() uint64 {
+	return u.Rule.Limit
+}
+
+// Percent returns the percentage of the limit used
+func (u QuotaUsage) Percent() float64 {
+	if u.Rule.Limit == 0 {
+		if u.Usage == 0 {
+			return 0
+		}
+		return 100
+	}
+	return float64(u.Usage) / float64(u.Rule.Limit) * 100
+}
+
+// Headroom returns the remaining usage before the limit is reached
+func (u QuotaUsage) Headroom() uint64 {
+	if u.Usage >= u.Rule.Limit {
+		return 0
+	}
+	return u.Rule.Limit - u.Usage
+}
+
+func (u QuotaUsage) human(v uint64) string {
+	if u.Rule.Op == QuotaData {
+		return byteToHuman(v)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// String returns the usage with human readable data sizes
+func (u QuotaUsage) String() string {
+	return fmt.Sprintf("P: %*v %*v %-7v %v.%v.%v Usage: %v Limit: %v Used: %.1f%% Headroom: %v",
+		prioPad, u.Rule.Prioirity, opPad, u.Rule.Op, u.Rule.QuotaType(),
+		u.ID[0], u.ID[1], u.ID[2], u.human(u.Usage), u.human(u.Rule.Limit),
+		u.Percent(), u.human(u.Headroom()))
+}
+
+// QuotaReport holds the usage for quota rules
+type QuotaReport []QuotaUsage
+
+// String returns the report with one usage per line
+func (r QuotaReport) String() string {
+	var b strings.Builder
+	for _, u := range r {
+		b.WriteString(u.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// GetQuotaReport returns the usage for every current quota rule.  General
+// rules have a usage for every matching totl id that has totals.
+func GetQuotaReport(f *os.File) (QuotaReport, error) {
+	rules, err := ReadQuotaRules(f)
+	if err != nil {
+		return nil, err
+	}
+	return QuotaReportFor(f, rules)
+}
+
+// QuotaReportFor returns the usage for the given quota rules
+func QuotaReportFor(f *os.File, rules RuleSet) (QuotaReport, error) {
+	var report QuotaReport
+	for _, q := range rules {
+		usage, err := quotaRuleUsage(f, q)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %v", FormatQuotaRule(q), err)
+		}
+		report = append(report, usage...)
+	}
+	return report, nil
+}
+
+func quotaRuleUsage(f *os.File, q QuotaRule) ([]QuotaUsage, error) {
+	// leading names with a fixed value bound the totals to read
+	fixed := 0
+	for fixed < 3 && (q.QuotaSource[fixed] == quotaLiteral ||
+		q.QuotaFlags[fixed]&quotaSelect != 0) {
+		fixed++
+	}
+
+	if fixed == 3 {
+		t, err := ReadXattrTotals(f, q.QuotaValue[0], q.QuotaValue[1], q.QuotaValue[2])
+		if err != nil {
+			return nil, err
+		}
+		return []QuotaUsage{{
+			Rule:  q,
+			ID:    q.QuotaValue,
+			Usage: quotaTotalUsage(q, t),
+		}}, nil
+	}
+
+	var start [3]uint64
+	copy(start[:fixed], q.QuotaValue[:fixed])
+
+	var usage []QuotaUsage
+	err := forEachTotal(f, start, func(t XattrTotal) bool {
+		for i := 0; i < fixed; i++ {
+			if t.ID[i] != q.QuotaValue[i] {
+				return false
+			}
+		}
+		for i := fixed; i < 3; i++ {
+			if (q.QuotaSource[i] == quotaLiteral ||
+				q.QuotaFlags[i]&quotaSelect != 0) &&
+				t.ID[i] != q.QuotaValue[i] {
+				return true
+			}
+		}
+		usage = append(usage, QuotaUsage{
+			Rule:  q,
+			ID:    t.ID,
+			Usage: quotaTotalUsage(q, t),
+		})
+		return true
+	})
+	return usage, err
+}
+
+func quotaTotalUsage(q QuotaRule, t XattrTotal) uint64 {
+	if q.Flags&quotaFlagCount != 0 {
+		return t.Count
+	}
+	return t.Total
+}
+
+// forEachTotal calls fn for each xattr total starting at pos until fn
+// returns false or there are no more totals
+func forEachTotal(f *os.File, pos [3]uint64, fn func(XattrTotal) bool) error {
+	totls := make([]xattrTotal, totalsBatch)
+	for {
+		query := readXattrTotals{
+			Pos_name:     pos,
+			Totals_ptr:   uint64(uintptr(unsafe.Pointer(&totls[0]))),
+			Totals_bytes: sizeofxattrTotal * uint64(len(totls)),
+		}
+
+		n, err := scoutfsctl(f, IOCREADXATTRTOTALS, unsafe.Pointer(&query))
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+
+		for i := 0; i < n; i++ {
+			if !fn(XattrTotal{
+				Total: totls[i].Total,
+				Count: totls[i].Count,
+				ID:    totls[i].Name,
+			}) {
+				return nil
+			}
+		}
+
+		pos = totls[n-1].Name
+		if pos[2] != math.MaxUint64 {
+			pos[2]++
+		} else if pos[1] != math.MaxUint64 {
+			pos[1]++
+			pos[2] = 0
+		} else if pos[0] != math.MaxUint64 {
+			pos[0]++
+			pos[1] = 0
+			pos[2] = 0
+		} else {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The scoutfs quota rule text syntax is:
+//
+//	PRIORITY VALUE,SOURCE,FLAGS VALUE,SOURCE,FLAGS VALUE,SOURCE,FLAGS OP LIMIT FLAGS
+//
+// SOURCE is L (literal), P (project), U (uid) or G (gid), name FLAGS is
+// S (select) or -, OP is I (inode) or D (data) and the rule FLAGS is
+// C (totl count) or -.  For example:
+//
+//	10 7,L,- 8,L,- 1000,U,S D 1073741824 -
+
+var quotaSourceChars = [...]byte{
+	quotaLiteral: 'L',
+	quotaProj:    'P',
+	quotaUID:     'U',
+	quotaGID:     'G',
+}
+
+// FormatQuotaRule returns the scoutfs quota rule text for the rule
+func FormatQuotaRule(q QuotaRule) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v", q.Prioirity)
+	for i := range q.QuotaValue {
+		src := byte('?')
+		if int(q.QuotaSource[i]) < len(quotaSourceChars) {
+			src = quotaSourceChars[q.QuotaSource[i]]
+		}
+		flag := byte('-')
+		if q.QuotaFlags[i]&quotaSelect != 0 {
+			flag = 'S'
+		}
+		fmt.Fprintf(&b, " %v,%c,%c", q.QuotaValue[i], src, flag)
+	}
+
+	op := byte('?')
+	switch q.Op {
+	case QuotaInode:
+		op = 'I'
+	case QuotaData:
+		op = 'D'
+	}
+	flag := byte('-')
+	if q.Flags&quotaFlagCount != 0 {
+		flag = 'C'
+	}
+	fmt.Fprintf(&b, " %c %v %c", op, q.Limit, flag)
+
+	return b.String()
+}
+
+// ParseQuotaRule parses a rule in the scoutfs quota rule text syntax
+func ParseQuotaRule(s string) (QuotaRule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 7 {
+		return QuotaRule{}, fmt.Errorf("parse rule %q: expected 7 fields, got %v",
+			s, len(fields))
+	}
+
+	var q QuotaRule
+	prio, err := strconv.ParseUint(fields[0], 10, 8)
+	if err != nil {
+		return QuotaRule{}, fmt.Errorf("parse priority %q: %v", fields[0], err)
+	}
+	q.Prioirity = uint8(prio)
+
+	for i := 0; i < 3; i++ {
+		err = parseQuotaName(fields[1+i], &q, i)
+		if err != nil {
+			return QuotaRule{}, err
+		}
+	}
+
+	switch fields[4] {
+	case "I":
+		q.Op = QuotaInode
+	case "D":
+		q.Op = QuotaData
+	default:
+		return QuotaRule{}, fmt.Errorf("parse op %q: must be I or D", fields[4])
+	}
+
+	q.Limit, err = strconv.ParseUint(fields[5], 10, 64)
+	if err != nil {
+		return QuotaRule{}, fmt.Errorf("parse limit %q: %v", fields[5], err)
+	}
+
+	switch fields[6] {
+	case "C":
+		q.Flags = quotaFlagCount
+	case "-":
+	default:
+		return QuotaRule{}, fmt.Errorf("parse flags %q: must be C or -", fields[6])
+	}
+
+	return q, nil
+}
+
+func parseQuotaName(s string, q *QuotaRule, i int) error {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return fmt.Errorf("parse name %q: expected VALUE,SOURCE,FLAGS", s)
+	}
+
+	val, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("parse name value %q: %v", parts[0], err)
+	}
+	q.QuotaValue[i] = val
+
+	found := false
+	for src, c := range quotaSourceChars {
+		if parts[1] == string(c) {
+			q.QuotaSource[i] = uint8(src)
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("parse name source %q: must be L, P, U or G", parts[1])
+	}
+
+	switch parts[2] {
+	case "S":
+		q.QuotaFlags[i] = quotaSelect
+	case "-":
+	default:
+		return fmt.Errorf("parse name flags %q: must be S or -", parts[2])
+	}
+
+	return nil
+}
+
+// ParseQuotaRules parses one rule per line in the scoutfs quota rule text
+// syntax.  Blank lines and lines starting with # are ignored, except for
+// the checksum comment written by WriteQuotaRules.  If it is present the
+// parsed rules must match the checksum.
+func ParseQuotaRules(r io.Reader) (RuleSet, error) {
+	var rules RuleSet
+	var checksum string
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(text, quotaChecksumComment) {
+			if checksum != "" {
+				return nil, fmt.Errorf("line %v: duplicate checksum", line)
+			}
+			checksum = strings.TrimSpace(strings.TrimPrefix(text, quotaChecksumComment))
+			continue
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		q, err := ParseQuotaRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", line, err)
+		}
+		rules = append(rules, q)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if checksum != "" {
+		sum := RuleSetChecksum(rules)
+		if sum != checksum {
+			return nil, fmt.Errorf("checksum mismatch: %v != %v", sum, checksum)
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestQuotaRuleRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want QuotaRule
+	}{
+		{
+			name: "uid data",
+			text: "10 7,L,- 8,L,- 1000,U,S D 1073741824 -",
+			want: QuotaRule{
+				Prioirity:   10,
+				QuotaValue:  [3]uint64{7, 8, 1000},
+				QuotaSource: [3]uint8{quotaLiteral, quotaLiteral, quotaUID},
+				QuotaFlags:  [3]uint8{0, 0, quotaSelect},
+				Op:          QuotaData,
+				Limit:       1073741824,
+			},
+		},
+		{
+			name: "gid inode count",
+			text: "255 0,P,S 1,G,S 0,L,- I 18446744073709551615 C",
+			want: QuotaRule{
+				Prioirity:   255,
+				QuotaValue:  [3]uint64{0, 1, 0},
+				QuotaSource: [3]uint8{quotaProj, quotaGID, quotaLiteral},
+				QuotaFlags:  [3]uint8{quotaSelect, quotaSelect, 0},
+				Op:          QuotaInode,
+				Limit:       18446744073709551615,
+				Flags:       quotaFlagCount,
+			},
+		},
+		{
+			name: "zero",
+			text: "0 0,L,- 0,L,- 0,L,- I 0 -",
+			want: QuotaRule{Op: QuotaInode},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseQuotaRule(tt.text)
+			if err != nil {
+				t.Fatalf("ParseQuotaRule(%q): %v", tt.text, err)
+			}
+			if q != tt.want {
+				t.Errorf("ParseQuotaRule(%q) = %+v, want %+v", tt.text, q, tt.want)
+			}
+			if s := FormatQuotaRule(q); s != tt.text {
+				t.Errorf("FormatQuotaRule() = %q, want %q", s, tt.text)
+			}
+		})
+	}
+}
+
+func TestParseQuotaRuleErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"empty", ""},
+		{"too few fields", "10 7,L,- 8,L,- 1000,U,S D 1073741824"},
+		{"too many fields", "10 7,L,- 8,L,- 1000,U,S D 1073741824 - -"},
+		{"priority range", "256 7,L,- 8,L,- 1000,U,S D 1 -"},
+		{"negative priority", "-1 7,L,- 8,L,- 1000,U,S D 1 -"},
+		{"name parts", "10 7,L 8,L,- 1000,U,S D 1 -"},
+		{"name value", "10 x,L,- 8,L,- 1000,U,S D 1 -"},
+		{"name source", "10 7,X,- 8,L,- 1000,U,S D 1 -"},
+		{"lowercase source", "10 7,l,- 8,L,- 1000,U,S D 1 -"},
+		{"name flags", "10 7,L,X 8,L,- 1000,U,S D 1 -"},
+		{"op", "10 7,L,- 8,L,- 1000,U,S X 1 -"},
+		{"limit", "10 7,L,- 8,L,- 1000,U,S D -1 -"},
+		{"limit range", "10 7,L,- 8,L,- 1000,U,S D 18446744073709551616 -"},
+		{"flags", "10 7,L,- 8,L,- 1000,U,S D 1 X"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseQuotaRule(tt.text)
+			if err == nil {
+				t.Errorf("ParseQuotaRule(%q) = %+v, want error", tt.text, q)
+			}
+		})
+	}
+}
+
+func TestParseQuotaRules(t *testing.T) {
+	rules := RuleSet{
+		{Prioirity: 10, QuotaValue: [3]uint64{7, 8, 1000},
+			QuotaSource: [3]uint8{quotaLiteral, quotaLiteral, quotaUID},
+			QuotaFlags:  [3]uint8{0, 0, quotaSelect},
+			Op:          QuotaData, Limit: 100},
+		{Prioirity: 20, QuotaValue: [3]uint64{0, 0, 5},
+			QuotaSource: [3]uint8{quotaLiteral, quotaLiteral, quotaGID},
+			QuotaFlags:  [3]uint8{0, 0, quotaSelect},
+			Op:          QuotaInode, Limit: 50, Flags: quotaFlagCount},
+	}
+
+	var b bytes.Buffer
+	err := WriteQuotaRules(&b, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := b.String()
+
+	tests := []struct {
+		name    string
+		text    string
+		want    int
+		wantErr bool
+	}{
+		{"written", written, 2, false},
+		{"comments and blanks", "# rules\n\n" + FormatQuotaRule(rules[0]) + "\n  \n", 1, false},
+		{"no checksum", strings.SplitN(written, "\n", 2)[1], 2, false},
+		{"changed rule", strings.Replace(written, " 100 ", " 101 ", 1), 0, true},
+		{"missing rule", strings.Join(strings.SplitN(written, "\n", 3)[:2], "\n"), 0, true},
+		{"duplicate checksum", strings.SplitN(written, "\n", 2)[0] + "\n" + written, 0, true},
+		{"bad rule", "10 7,L,- 8,L,- 1000,U,S D\n", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuotaRules(strings.NewReader(tt.text))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQuotaRules() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Errorf("ParseQuotaRules() = %v rules, want %v", len(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// quotaRulesBatch is the number of rules read per GetQuotaRules call
+const quotaRulesBatch = 1024
+
+// ReadQuotaRules returns all current quota rules in the order the
+// filesystem would match them
+func ReadQuotaRules(f *os.File) (RuleSet, error) {
+	q, err := GetQuotaRules(f, quotaRulesBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules RuleSet
+	for {
+		r, err := q.Next()
+		if err != nil {
+			return nil, fmt.Errorf("get quota rules: %v", err)
+		}
+		if r == nil {
+			break
+		}
+		rules = append(rules, r...)
+	}
+
+	sort.Sort(rules)
+	return rules, nil
+}
+
+// QuotaUpdate is a rule whose limit changes
+type QuotaUpdate struct {
+	Old QuotaRule
+	New QuotaRule
+}
+
+// QuotaPlan holds the changes needed to make the current rules match the
+// desired rules
+type QuotaPlan struct {
+	Add    RuleSet
+	Update []QuotaUpdate
+	Delete RuleSet
+}
+
+// Empty returns true if there are no changes in the plan
+func (p QuotaPlan) Empty() bool {
+	return len(p.Add) == 0 && len(p.Update) == 0 && len(p.Delete) == 0
+}
+
+// String returns the plan as one change per line in the order they are
+// applied, prefixed with + for adds, ~ for updates and - for deletes
+func (p QuotaPlan) String() string {
+	var b strings.Builder
+	for _, q := range p.Add {
+		fmt.Fprintf(&b, "+ %v\n", FormatQuotaRule(q))
+	}
+	for _, u := range p.Update {
+		fmt.Fprintf(&b, "~ %v (limit %v -> %v)\n",
+			FormatQuotaRule(u.New), u.Old.Limit, u.New.Limit)
+	}
+	for _, q := range p.Delete {
+		fmt.Fprintf(&b, "- %v\n", FormatQuotaRule(q))
+	}
+	return b.String()
+}
+
+// quotaIdentity returns the rule without its limit, rules with the same
+// identity are considered the same rule with a changed limit
+func quotaIdentity(q QuotaRule) QuotaRule {
+	q.Limit = 0
+	return q
+}
+
+// PlanQuotaReconcile computes the changes needed to make current match
+// desired.  Rules in desired must have unique identities.
+func PlanQuotaReconcile(current, desired RuleSet) (QuotaPlan, error) {
+	want := make(map[QuotaRule]QuotaRule, len(desired))
+	for _, q := range desired {
+		id := quotaIdentity(q)
+		if _, ok := want[id]; ok {
+			return QuotaPlan{}, fmt.Errorf("duplicate desired rule %q",
+				FormatQuotaRule(q))
+		}
+		want[id] = q
+	}
+
+	// current may hold several rules with the same identity and
+	// different limits, keep the one with the desired limit if present
+	exact := make(map[QuotaRule]bool, len(current))
+	for _, q := range current {
+		if d, ok := want[quotaIdentity(q)]; ok && d.Limit == q.Limit {
+			exact[quotaIdentity(q)] = true
+		}
+	}
+
+	var plan QuotaPlan
+	have := make(map[QuotaRule]bool, len(current))
+	for _, q := range current {
+		id := quotaIdentity(q)
+		d, ok := want[id]
+		if !ok || have[id] || (exact[id] && d.Limit != q.Limit) {
+			plan.Delete = append(plan.Delete, q)
+			continue
+		}
+		if d.Limit != q.Limit {
+			plan.Update = append(plan.Update, QuotaUpdate{Old: q, New: d})
+		}
+		have[id] = true
+	}
+
+	for _, q := range desired {
+		if !have[quotaIdentity(q)] {
+			plan.Add = append(plan.Add, q)
+		}
+	}
+
+	sort.Sort(plan.Add)
+	sort.Sort(plan.Delete)
+	return plan, nil
+}
+
+// Apply applies the plan.  New rules are added first and old rules are
+// deleted last so that there is no window where a file has no applicable
+// rule.  Updates add the new limit before deleting the old limit, if the
+// filesystem rejects the new limit while the old limit exists an error
+// is returned rather than removing the old limit first.
+func (p QuotaPlan) Apply(f *os.File) error {
+	return p.apply(
+		func(q QuotaRule) error { return quotaAdd(f, q) },
+		func(q QuotaRule) error { return QuotaDelete(f, q) })
+}
+
+// apply applies the plan in order with the add and delete functions
+func (p QuotaPlan) apply(add, del func(QuotaRule) error) error {
+	for _, q := range p.Add {
+		err := add(q)
+		if err != nil {
+			return fmt.Errorf("add rule %q: %v", FormatQuotaRule(q), err)
+		}
+	}
+
+	for _, u := range p.Update {
+		err := add(u.New)
+		if err == syscall.EEXIST {
+			return fmt.Errorf("add rule %q: conflicts with existing rule %q: %v",
+				FormatQuotaRule(u.New), FormatQuotaRule(u.Old), err)
+		}
+		if err != nil {
+			return fmt.Errorf("add rule %q: %v", FormatQuotaRule(u.New), err)
+		}
+		err = del(u.Old)
+		if err != nil {
+			return fmt.Errorf("delete rule %q: %v", FormatQuotaRule(u.Old), err)
+		}
+	}
+
+	for _, q := range p.Delete {
+		err := del(q)
+		if err != nil {
+			return fmt.Errorf("delete rule %q: %v", FormatQuotaRule(q), err)
+		}
+	}
+
+	return nil
+}
+
+// Reconcile reads the current quota rules and applies the changes needed
+// to match the desired rules.  If dryRun is set the plan is returned
+// without applying any changes.
+func Reconcile(f *os.File, desired RuleSet, dryRun bool) (QuotaPlan, error) {
+	current, err := ReadQuotaRules(f)
+	if err != nil {
+		return QuotaPlan{}, err
+	}
+
+	plan, err := PlanQuotaReconcile(current, desired)
+	if err != nil {
+		return QuotaPlan{}, err
+	}
+	if dryRun || plan.Empty() {
+		return plan, nil
+	}
+
+	return plan, plan.Apply(f)
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// parseRules returns the valid rules in the quota rule text syntax
+func parseRules(t *testing.T, texts ...string) RuleSet {
+	t.Helper()
+	var rules RuleSet
+	for _, s := range texts {
+		q, err := ParseQuotaRule(s)
+		if err == nil {
+			err = ValidateQuotaRule(q)
+		}
+		if err != nil {
+			t.Fatalf("rule %q: %v", s, err)
+		}
+		rules = append(rules, q)
+	}
+	return rules
+}
+
+const (
+	ruleUID   = "10 0,L,- 0,L,- 1000,U,S D 100 -"
+	ruleUID2  = "10 0,L,- 0,L,- 1000,U,S D 200 -"
+	ruleUID3  = "10 0,L,- 0,L,- 1000,U,S D 300 -"
+	ruleGID   = "20 0,L,- 0,L,- 5,G,S I 50 C"
+	ruleProj  = "30 0,L,- 0,L,- 7,P,S D 1000 -"
+	ruleGen   = "1 0,L,- 0,L,- 0,U,- D 10 -"
+	ruleGenIn = "1 0,L,- 0,L,- 0,U,- I 10 C"
+)
+
+func TestPlanQuotaReconcile(t *testing.T) {
+	tests := []struct {
+		name    string
+		current []string
+		desired []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:    "identical",
+			current: []string{ruleUID, ruleGID},
+			desired: []string{ruleGID, ruleUID},
+		},
+		{
+			name:    "add",
+			current: []string{ruleUID},
+			desired: []string{ruleUID, ruleGen, ruleProj},
+			want:    "+ " + ruleProj + "\n+ " + ruleGen + "\n",
+		},
+		{
+			name:    "delete",
+			current: []string{ruleGen, ruleUID, ruleGID},
+			desired: []string{ruleUID},
+			want:    "- " + ruleGID + "\n- " + ruleGen + "\n",
+		},
+		{
+			name:    "update limit",
+			current: []string{ruleUID},
+			desired: []string{ruleUID2},
+			want:    "~ " + ruleUID2 + " (limit 100 -> 200)\n",
+		},
+		{
+			name:    "op is part of identity",
+			current: []string{ruleGen},
+			desired: []string{ruleGenIn},
+			want:    "+ " + ruleGenIn + "\n- " + ruleGen + "\n",
+		},
+		{
+			name:    "duplicate current keeps exact limit",
+			current: []string{ruleUID, ruleUID2, ruleUID3},
+			desired: []string{ruleUID2},
+			want:    "- " + ruleUID3 + "\n- " + ruleUID + "\n",
+		},
+		{
+			name:    "duplicate current updates first",
+			current: []string{ruleUID, ruleUID3},
+			desired: []string{ruleUID2},
+			want: "~ " + ruleUID2 + " (limit 100 -> 200)\n" +
+				"- " + ruleUID3 + "\n",
+		},
+		{
+			name:    "mixed",
+			current: []string{ruleUID, ruleGID},
+			desired: []string{ruleUID2, ruleProj},
+			want: "+ " + ruleProj + "\n" +
+				"~ " + ruleUID2 + " (limit 100 -> 200)\n" +
+				"- " + ruleGID + "\n",
+		},
+		{
+			name:    "duplicate desired",
+			desired: []string{ruleUID, ruleUID2},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := PlanQuotaReconcile(parseRules(t, tt.current...),
+				parseRules(t, tt.desired...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PlanQuotaReconcile() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := plan.String(); got != tt.want {
+				t.Errorf("plan:\n%vwant:\n%v", got, tt.want)
+			}
+			if plan.Empty() != (tt.want == "") {
+				t.Errorf("Empty() = %v", plan.Empty())
+			}
+		})
+	}
+}
+
+func TestQuotaPlanApplyOrder(t *testing.T) {
+	plan, err := PlanQuotaReconcile(parseRules(t, ruleUID, ruleGID),
+		parseRules(t, ruleUID2, ruleProj))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		fail    string
+		failErr error
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "success",
+			want: []string{"+ " + ruleProj, "+ " + ruleUID2, "- " + ruleUID,
+				"- " + ruleGID},
+		},
+		{
+			name:    "update conflict keeps old rule",
+			fail:    "+ " + ruleUID2,
+			failErr: syscall.EEXIST,
+			want:    []string{"+ " + ruleProj, "+ " + ruleUID2},
+			wantErr: true,
+		},
+		{
+			name:    "add failure stops before deletes",
+			fail:    "+ " + ruleProj,
+			failErr: syscall.EINVAL,
+			want:    []string{"+ " + ruleProj},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ops []string
+			op := func(prefix string) func(QuotaRule) error {
+				return func(q QuotaRule) error {
+					s := prefix + FormatQuotaRule(q)
+					ops = append(ops, s)
+					if s == tt.fail {
+						return tt.failErr
+					}
+					return nil
+				}
+			}
+
+			err := plan.apply(op("+ "), op("- "))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("apply() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got, want := strings.Join(ops, "\n"), strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("ops:\n%v\nwant:\n%v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// RetentionXattr is the hidden xattr recording when retention for a
+	// file expires.  It is tagged srch so that retained files can be found
+	// with an XattrQuery.
+	RetentionXattr = "scoutfs.hide.srch.retention_until"
+)
+
+// ErrRetained is returned for operations refused on retained files
+var ErrRetained = errors.New("file is under retention")
+
+// ErrRetentionUnset is matched by SetRetentionUntil errors that leave the
+// file without retention, retention should be set again
+var ErrRetentionUnset = errors.New("retention left unset")
+
+// RetentionError describes an operation refused on a retained file
+type RetentionError struct {
+	Op    string
+	Ino   uint64
+	Until time.Time
+}
+
+func (e *RetentionError) Error() string {
+	if e.Until.IsZero() {
+		return fmt.Sprintf("%v inode %v: %v", e.Op, e.Ino, ErrRetained)
+	}
+	return fmt.Sprintf("%v inode %v: %v until %v", e.Op, e.Ino, ErrRetained,
+		e.Until.UTC().Format(time.RFC3339))
+}
+
+// Is matches ErrRetained
+func (e *RetentionError) Is(target error) bool {
+	return target == ErrRetained
+}
+
+// checkRetention returns a RetentionError if file handle is retained.
+// Kernels without retention support never have retained files.
+func checkRetention(f *os.File, op string) error {
+	retained, err := GetRetention(f)
+	if err == syscall.ENOTTY || err == errEINVAL {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get retention: %v", err)
+	}
+	if !retained {
+		return nil
+	}
+
+	rerr := &RetentionError{Op: op}
+	fi, err := f.Stat()
+	if err == nil {
+		rerr.Ino, _ = fileIno(fi)
+	}
+	rerr.Until, _ = GetRetentionUntil(f)
+	return rerr
+}
+
+// retentionErr returns a RetentionError for the first retained file if
+// the kernel refused an operation with EPERM, otherwise err.  Retention
+// is only checked after a failure so that successful operations don't
+// pay for the extra ioctls.
+func retentionErr(err error, op string, files ...*os.File) error {
+	if err != syscall.EPERM {
+		return err
+	}
+	for _, f := range files {
+		rerr := checkRetention(f, op)
+		if errors.Is(rerr, ErrRetained) {
+			return rerr
+		}
+	}
+	return err
+}
+
+// GetRetentionUntil returns the recorded retention expiry for file handle
+// syscall.ENODATA is returned if no expiry has been recorded
+func GetRetentionUntil(f *os.File) (time.Time, error) {
+	b := make([]byte, 32)
+	n, err := fgetxattr(f, RetentionXattr, b)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	sec, err := strconv.ParseInt(string(b[:n]), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse retention %q: %v", b[:n], err)
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// SetRetentionUntil records the retention expiry and sets retention on
+// file handle.  The expiry of an already retained file can only be
+// extended, moving it earlier is refused with an error matching
+// ErrRetained.
+//
+// The expiry xattr can't be changed while the file is retained, so
+// updating an already retained file clears retention, sets the xattr and
+// sets retention again.  The file is not protected while this runs, a
+// concurrent unlink, truncate or write of the file can succeed.  If
+// retention can't be set again the error matches ErrRetentionUnset.
+func SetRetentionUntil(f *os.File, until time.Time) error {
+	retained, err := GetRetention(f)
+	if err != nil {
+		return fmt.Errorf("get retention: %v", err)
+	}
+	if retained {
+		cur, err := GetRetentionUntil(f)
+		if err != nil && err != syscall.ENODATA {
+			return err
+		}
+		if err == nil && until.Unix() < cur.Unix() {
+			rerr := &RetentionError{Op: "shorten retention", Until: cur}
+			if fi, err := f.Stat(); err == nil {
+				rerr.Ino, _ = fileIno(fi)
+			}
+			return rerr
+		}
+		if err == nil && until.Unix() == cur.Unix() {
+			return nil
+		}
+
+		err = ClearRetention(f)
+		if err != nil {
+			return fmt.Errorf("clear retention: %v", err)
+		}
+	}
+
+	err = fsetxattr(f, RetentionXattr,
+		[]byte(strconv.FormatInt(until.Unix(), 10)), 0)
+	if err != nil {
+		if retained {
+			if serr := SetRetention(f); serr != nil {
+				return fmt.Errorf("set retention xattr: %v (restore retention: %v): %w",
+					err, serr, ErrRetentionUnset)
+			}
+		}
+		return fmt.Errorf("set retention xattr: %v", err)
+	}
+
+	err = SetRetention(f)
+	if err != nil {
+		return fmt.Errorf("set retention: %v: %w", err, ErrRetentionUnset)
+	}
+	return nil
+}
+
+// ApplyRetentionTree sets retention with expiry on all regular files
+// under root, returning the number of files updated
+func ApplyRetentionTree(root string, until time.Time) (uint64, error) {
+	var count uint64
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		err = SetRetentionUntil(f, until)
+		if err != nil {
+			return fmt.Errorf("%v: %w", path, err)
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// ApplyRetentionXattr sets retention with expiry on all inodes returned by
+// the xattr query, returning the number of files updated
+// An open file within scoutfs is supplied for ioctls
+// (usually just the base mount point directory)
+func ApplyRetentionXattr(dirfd *os.File, q *XattrQuery, until time.Time) (uint64, error) {
+	var count uint64
+	for {
+		inodes, err := q.Next()
+		if err != nil {
+			return count, err
+		}
+		if inodes == nil {
+			return count, nil
+		}
+		for _, ino := range inodes {
+			ok, err := applyRetentionIno(dirfd, ino, until)
+			if err != nil {
+				return count, err
+			}
+			if ok {
+				count++
+			}
+		}
+	}
+}
+
+// ApplyRetentionIndex sets retention with expiry on all inodes returned by
+// the index search, returning the number of files updated
+// An open file within scoutfs is supplied for ioctls
+// (usually just the base mount point directory)
+func ApplyRetentionIndex(dirfd *os.File, s *IndexSearch, until time.Time) (uint64, error) {
+	var count uint64
+	for {
+		ents, err := s.Next()
+		if err != nil {
+			return count, err
+		}
+		if ents == nil {
+			return count, nil
+		}
+		for _, e := range ents {
+			ok, err := applyRetentionIno(dirfd, e.Inode, until)
+			if err != nil {
+				return count, err
+			}
+			if ok {
+				count++
+			}
+		}
+	}
+}
+
+// applyRetentionIno returns false if the inode no longer exists
+func applyRetentionIno(dirfd *os.File, ino uint64, until time.Time) (bool, error) {
+	f, err := OpenByID(dirfd, ino, os.O_RDONLY, "")
+	if err == syscall.ENOENT || err == syscall.ESTALE {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("open inode %v: %v", ino, err)
+	}
+	defer f.Close()
+
+	err = SetRetentionUntil(f, until)
+	if err != nil {
+		return false, fmt.Errorf("inode %v: %w", ino, err)
+	}
+	return true, nil
+}
+
+// RetentionEntry is a retained inode and its recorded expiry
+type RetentionEntry struct {
+	Ino   uint64
+	Until time.Time
+}
+
+// ExpiredRetention returns the retained inodes with a recorded expiry at
+// or before now
+// An open file within scoutfs is supplied for ioctls
+// (usually just the base mount point directory)
+func ExpiredRetention(dirfd *os.File, now time.Time) ([]RetentionEntry, error) {
+	q := NewXattrQuery(dirfd, RetentionXattr)
+
+	var expired []RetentionEntry
+	for {
+		inodes, err := q.Next()
+		if err != nil {
+			return nil, err
+		}
+		if inodes == nil {
+			return expired, nil
+		}
+		for _, ino := range inodes {
+			e, ok, err := expiredRetentionIno(dirfd, ino, now)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				expired = append(expired, e)
+			}
+		}
+	}
+}
+
+func expiredRetentionIno(dirfd *os.File, ino uint64, now time.Time) (RetentionEntry, bool, error) {
+	f, err := OpenByID(dirfd, ino, os.O_RDONLY, "")
+	if err == syscall.ENOENT || err == syscall.ESTALE {
+		return RetentionEntry{}, false, nil
+	}
+	if err != nil {
+		return RetentionEntry{}, false, fmt.Errorf("open inode %v: %v", ino, err)
+	}
+	defer f.Close()
+
+	retained, err := GetRetention(f)
+	if err != nil {
+		return RetentionEntry{}, false, fmt.Errorf("inode %v: get retention: %v", ino, err)
+	}
+	if !retained {
+		return RetentionEntry{}, false, nil
+	}
+
+	until, err := GetRetentionUntil(f)
+	if err == syscall.ENODATA {
+		return RetentionEntry{}, false, nil
+	}
+	if err != nil {
+		return RetentionEntry{}, false, fmt.Errorf("inode %v: %v", ino, err)
+	}
+	if until.After(now) {
+		return RetentionEntry{}, false, nil
+	}
+
+	return RetentionEntry{Ino: ino, Until: until}, true, nil
+}
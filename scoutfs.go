@@ -7,7 +7,6 @@
 package scoutfs
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/binary"
 	"errors"
@@ -15,8 +14,6 @@ import (
 	"io"
 	"math"
 	"os"
-	"path/filepath"
-	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -310,6 +307,7 @@ func ReleaseFile(path string, version uint64) error {
 }
 
 // FReleaseFile set file offline by freeing associated extents
+// Retained files are refused with an error matching ErrRetained.
 func FReleaseFile(f *os.File, version uint64) error {
 	fi, err := f.Stat()
 	if err != nil {
@@ -322,7 +320,7 @@ func FReleaseFile(f *os.File, version uint64) error {
 	}
 
 	_, err = scoutfsctl(f, IOCRELEASE, unsafe.Pointer(&r))
-	return err
+	return retentionErr(err, "release", f)
 }
 
 func divRoundUp(size, bs uint64) uint64 {
@@ -347,6 +345,7 @@ func ReleaseBlocks(path string, offset, length, version uint64) error {
 
 // FReleaseBlocks marks blocks offline and frees associated extents
 // offset/length must be 4k aligned
+// Retained files are refused with an error matching ErrRetained.
 func FReleaseBlocks(f *os.File, offset, length, version uint64) error {
 	r := iocRelease{
 		Offset:  offset,
@@ -355,7 +354,7 @@ func FReleaseBlocks(f *os.File, offset, length, version uint64) error {
 	}
 
 	_, err := scoutfsctl(f, IOCRELEASE, unsafe.Pointer(&r))
-	return err
+	return retentionErr(err, "release", f)
 }
 
 // StageFile rehydrates offline file
@@ -688,60 +687,19 @@ func GetQuorumInfo(path string) (QuorumInfo, error) {
 		return QuorumInfo{}, fmt.Errorf("error GetIDs: %v", err)
 	}
 
-	sfspath := filepath.Join(sysscoutfs, id.ShortID, statusfile)
-	sfs, err := os.Open(sfspath)
-	if err != nil {
-		return QuorumInfo{}, fmt.Errorf("open %q: %v", sfspath, err)
-	}
-	defer sfs.Close()
-
-	qi := QuorumInfo{}
-	scanner := bufio.NewScanner(sfs)
-	for scanner.Scan() {
-		fields := strings.Fields(scanner.Text())
-		if len(fields) < 2 {
-			return QuorumInfo{}, fmt.Errorf("parse line (%q): %q",
-				sfspath, scanner.Text())
-		}
-		switch fields[0] {
-		case "quorum_slot_nr":
-			qi.Slot, err = strconv.ParseInt(fields[1], 10, 64)
-			if err != nil {
-				return QuorumInfo{}, fmt.Errorf("parse quorum_slot_nr %q: %v",
-					fields[1], err)
-			}
-		case "term":
-			qi.Term, err = strconv.ParseInt(fields[1], 10, 64)
-			if err != nil {
-				return QuorumInfo{}, fmt.Errorf("term %q: %v",
-					fields[1], err)
-			}
-		case "role":
-			if len(fields) < 3 {
-				return QuorumInfo{}, fmt.Errorf("parse line (%q): %q",
-					sfspath, scanner.Text())
-			}
-			qi.Role = fields[2]
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return QuorumInfo{}, fmt.Errorf("parse %q: %v", sfspath, err)
-	}
-
-	return qi, nil
+	return NewSysfs().Quorum(id.ShortID)
 }
 
 // DiskUsage holds usage information reported by the filesystem
 type DiskUsage struct {
-	TotalMetaBlocks uint64
-	FreeMetaBlocks  uint64
-	TotalDataBlocks uint64
-	FreeDataBlocks  uint64
+	TotalMetaBlocks    uint64
+	FreeMetaBlocks     uint64
+	TotalDataBlocks    uint64
+	FreeDataBlocks     uint64
+	ReservedMetaBlocks uint64
 }
 
 var dfBatchCount uint64 = 4096
-var metaFlag uint8 = 0x1
 
 // GetDF returns usage data for the filesystem
 func GetDF(f *os.File) (DiskUsage, error) {
@@ -752,46 +710,26 @@ func GetDF(f *os.File) (DiskUsage, error) {
 		return DiskUsage{}, fmt.Errorf("statfs more: %v", err)
 	}
 
-	nr := dfBatchCount
-	buf := make([]byte, int(unsafe.Sizeof(allocDetailEntry{}))*int(nr))
-	var ret int
-	for {
-		ad := allocDetail{
-			Nr:  nr,
-			Ptr: uint64(uintptr(unsafe.Pointer(&buf[0]))),
-		}
-		ret, err = scoutfsctl(f, IOCALLOCDETAIL, unsafe.Pointer(&ad))
-		if err == syscall.EOVERFLOW {
-			nr = nr * 2
-			buf = make([]byte, int(unsafe.Sizeof(allocDetailEntry{}))*int(nr))
-			continue
-		}
-		if err != nil {
-			return DiskUsage{}, fmt.Errorf("alloc detail: %v", err)
-		}
-		break
+	entries, err := AllocDetail(f)
+	if err != nil {
+		return DiskUsage{}, err
 	}
 
-	rbuf := bytes.NewReader(buf)
-	var ade allocDetailEntry
 	var metaFree, dataFree uint64
-	for i := 0; i < ret; i++ {
-		err := binary.Read(rbuf, binary.LittleEndian, &ade)
-		if err != nil {
-			return DiskUsage{}, fmt.Errorf("parse alloc detail results: %v", err)
-		}
-		if ade.Flags&metaFlag != 0 {
-			metaFree += ade.Blocks
+	for _, e := range entries {
+		if e.Meta {
+			metaFree += e.Blocks
 		} else {
-			dataFree += ade.Blocks
+			dataFree += e.Blocks
 		}
 	}
 
 	return DiskUsage{
-		TotalMetaBlocks: stfs.Total_meta_blocks,
-		FreeMetaBlocks:  metaFree,
-		TotalDataBlocks: stfs.Total_data_blocks,
-		FreeDataBlocks:  dataFree,
+		TotalMetaBlocks:    stfs.Total_meta_blocks,
+		FreeMetaBlocks:     metaFree,
+		TotalDataBlocks:    stfs.Total_data_blocks,
+		FreeDataBlocks:     dataFree,
+		ReservedMetaBlocks: stfs.Reserved_meta_blocks,
 	}, nil
 }
 
@@ -810,6 +748,8 @@ func GetDF(f *os.File) (DiskUsage, error) {
 // EXDEV: the source and destination files are in different filesystems.
 // EISDIR: either the source or destination is a directory.
 // ENODATA: either the source or destination file have offline extents.
+// Retained source or destination files are refused with an error matching
+// ErrRetained.
 func MoveData(from, to *os.File) error {
 	ffi, err := from.Stat()
 	if err != nil {
@@ -829,7 +769,7 @@ func MoveData(from, to *os.File) error {
 
 	_, err = scoutfsctl(to, IOCMOVEBLOCKS, unsafe.Pointer(&mb))
 	if err != nil {
-		return err
+		return retentionErr(err, "move", from, to)
 	}
 
 	from.Truncate(0)
@@ -853,6 +793,8 @@ func MoveData(from, to *os.File) error {
 // EXDEV: the source and destination files are in different filesystems.
 // EISDIR: either the source or destination is a directory.
 // ENODATA: either the source or destination file have offline extents.
+// Retained source or destination files are refused with an error matching
+// ErrRetained.
 func StageMove(from, to *os.File, offset, version uint64) error {
 	ffi, err := from.Stat()
 	if err != nil {
@@ -870,7 +812,7 @@ func StageMove(from, to *os.File, offset, version uint64) error {
 
 	_, err = scoutfsctl(to, IOCMOVEBLOCKS, unsafe.Pointer(&mb))
 	if err != nil {
-		return err
+		return retentionErr(err, "move", from, to)
 	}
 
 	from.Truncate(0)
@@ -894,6 +836,8 @@ func StageMove(from, to *os.File, offset, version uint64) error {
 // EXDEV: the source and destination files are in different filesystems.
 // EISDIR: either the source or destination is a directory.
 // ENODATA: either the source or destination file have offline extents.
+// Retained source or destination files are refused with an error matching
+// ErrRetained.
 func StageMoveAt(from, to *os.File, len, fromOffset, toOffset, version uint64) error {
 	mb := moveBlocks{
 		From_fd:      uint64(from.Fd()),
@@ -906,7 +850,7 @@ func StageMoveAt(from, to *os.File, len, fromOffset, toOffset, version uint64) e
 
 	_, err := scoutfsctl(to, IOCMOVEBLOCKS, unsafe.Pointer(&mb))
 	if err != nil {
-		return err
+		return retentionErr(err, "move", from, to)
 	}
 
 	return nil
@@ -1042,42 +986,65 @@ func GetParents(dirfd *os.File, ino uint64, b []byte) ([]Parent, error) {
 		b = make([]byte, getparentBufsize)
 	}
 
-	gre := getReferringEntries{}
-
-	gre.Entries_bytes = uint64(len(b))
-	gre.Entries_ptr = uint64(uintptr(unsafe.Pointer(&b[0])))
-	gre.Ino = ino
-
 	var parents []Parent
-
+	var dirIno, dirPos uint64
 	for {
-		n, err := scoutfsctl(dirfd, IOCGETREFERRINGENTRIES, unsafe.Pointer(&gre))
+		ents, isLast, err := readParents(dirfd, ino, dirIno, dirPos, b)
 		if err != nil {
 			return nil, err
 		}
-		if n == 0 {
+		if len(ents) == 0 {
 			break
 		}
 
-		ents, isLast, err := parseDents(b)
-		if err != nil {
-			return nil, err
-		}
-
 		parents = append(parents, ents...)
 		if isLast {
 			break
 		}
+
+		// resume after the last returned entry
+		last := ents[len(ents)-1]
+		dirIno, dirPos = last.Ino, last.Pos+1
+		if dirPos == 0 {
+			dirIno++
+			if dirIno == 0 {
+				break
+			}
+		}
 	}
 
 	return parents, nil
 }
 
-func parseDents(b []byte) ([]Parent, bool, error) {
+// readParents reads a batch of the entries referring to ino, starting
+// from the entry at dirIno and dirPos, and returns true if the last
+// entry was read
+func readParents(dirfd *os.File, ino, dirIno, dirPos uint64, b []byte) ([]Parent, bool, error) {
+	gre := getReferringEntries{
+		Ino:           ino,
+		Dir_ino:       dirIno,
+		Dir_pos:       dirPos,
+		Entries_ptr:   uint64(uintptr(unsafe.Pointer(&b[0]))),
+		Entries_bytes: uint64(len(b)),
+	}
+
+	n, err := scoutfsctl(dirfd, IOCGETREFERRINGENTRIES, unsafe.Pointer(&gre))
+	if err != nil {
+		return nil, false, err
+	}
+	if n == 0 {
+		return nil, true, nil
+	}
+
+	return parseDents(b, n)
+}
+
+// parseDents parses up to n entries from the buffer
+func parseDents(b []byte, n int) ([]Parent, bool, error) {
 	r := bytes.NewReader(b)
-	var parents []Parent
+	parents := make([]Parent, 0, n)
 	var isLast bool
-	for {
+	for len(parents) < n && r.Len() > 0 {
 		var err error
 		var parent Parent
 		parent, isLast, err = parseDent(r)
@@ -1091,9 +1058,6 @@ func parseDents(b []byte) ([]Parent, bool, error) {
 		if isLast {
 			break
 		}
-		if r.Len() == 0 {
-			break
-		}
 	}
 	return parents, isLast, nil
 }
@@ -1725,8 +1689,11 @@ func NewIndexSearch(f *os.File, key uint8, start, end uint64, opts ...IOption) *
 	for _, opt := range opts {
 		opt(i)
 	}
+	if i.batch == 0 {
+		i.batch = indexXattrBatch
+	}
 
-	i.buf = make([]byte, int(unsafe.Sizeof(indexEntry{}))*indexXattrBatch)
+	i.buf = make([]byte, int(unsafe.Sizeof(indexEntry{}))*int(i.batch))
 
 	return i
 }
@@ -1766,7 +1733,7 @@ func (i *IndexSearch) Next() ([]IndexEnt, error) {
 		First: i.pos,
 		Last:  i.end,
 		Ptr:   uint64(uintptr(unsafe.Pointer(&i.buf[0]))),
-		Nr:    indexXattrBatch,
+		Nr:    i.batch,
 	}
 
 	n, err := scoutfsctl(i.f, IOCREADXATTRINDEX, unsafe.Pointer(&query))
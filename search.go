@@ -0,0 +1,338 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"os"
+	"sync"
+)
+
+// searchBatch is the default number of inodes returned per Next() call
+const searchBatch = 1024
+
+// InodeSource is a stream of inode numbers in ascending order,
+// returning nil when complete.  XattrQuery is an InodeSource.
+type InodeSource interface {
+	Next() ([]uint64, error)
+}
+
+// SearchExpression combines sorted inode streams with set operations.
+// Each source is read concurrently in its own goroutine and the streams
+// are merged without holding the full result sets in memory.  The
+// goroutines exit once Next returns an error or the final empty batch,
+// callers that stop reading before then must call Close, usually with
+// defer, or the goroutines are left blocked.
+type SearchExpression struct {
+	node  searchNode
+	batch int
+}
+
+// NewSearchExpression creates an expression matching inodes with the
+// .srch. xattr key, options are passed to NewXattrQuery
+// An open file within scoutfs is supplied for ioctls
+// (usually just the base mount point directory)
+func NewSearchExpression(f *os.File, key string, opts ...XOption) *SearchExpression {
+	return SearchSource(NewXattrQuery(f, key, opts...))
+}
+
+// SearchSource creates an expression matching the inodes from src
+func SearchSource(src InodeSource) *SearchExpression {
+	return newSearchExpression(&leafNode{src: src})
+}
+
+// SearchAnd matches inodes in all of the expressions.  The expressions
+// must not be used on their own after being combined.
+func SearchAnd(exprs ...*SearchExpression) *SearchExpression {
+	return newSearchExpression(&andNode{children: searchNodes(exprs)})
+}
+
+// SearchOr matches inodes in any of the expressions.  The expressions
+// must not be used on their own after being combined.
+func SearchOr(exprs ...*SearchExpression) *SearchExpression {
+	return newSearchExpression(&orNode{children: searchNodes(exprs)})
+}
+
+// SearchDifference matches inodes in a that are not in b.  The
+// expressions must not be used on their own after being combined.
+func SearchDifference(a, b *SearchExpression) *SearchExpression {
+	return newSearchExpression(&diffNode{a: a.node, b: b.node})
+}
+
+func newSearchExpression(n searchNode) *SearchExpression {
+	return &SearchExpression{node: n, batch: searchBatch}
+}
+
+func searchNodes(exprs []*SearchExpression) []searchNode {
+	nodes := make([]searchNode, len(exprs))
+	for i, e := range exprs {
+		nodes[i] = e.node
+	}
+	return nodes
+}
+
+// SetBatchSize sets the max number of inodes to be returned at a time
+func (e *SearchExpression) SetBatchSize(size int) {
+	if size > 0 {
+		e.batch = size
+	}
+}
+
+// Next gets the next batch of matching inodes in ascending order, returns
+// nil when complete.  The expression is closed when it is complete or
+// returns an error.
+func (e *SearchExpression) Next() ([]uint64, error) {
+	var inodes []uint64
+	for len(inodes) < e.batch {
+		ino, ok, err := e.node.peek()
+		if err != nil {
+			e.Close()
+			return nil, err
+		}
+		if !ok {
+			// sources not needed to finish may still be reading
+			e.Close()
+			break
+		}
+		inodes = append(inodes, ino)
+		e.node.pop()
+	}
+	return inodes, nil
+}
+
+// Close stops reading from all sources and waits for any reads in
+// progress, so the sources' files can be closed once it returns.  It is
+// safe to call more than once and after the expression is complete.
+func (e *SearchExpression) Close() {
+	e.node.close()
+}
+
+// searchNode is a cursor over an ascending stream of unique inodes
+type searchNode interface {
+	// peek returns the current inode, false when exhausted
+	peek() (uint64, bool, error)
+	// pop advances past the current inode
+	pop()
+	close()
+}
+
+type leafBatch struct {
+	inodes []uint64
+	err    error
+}
+
+// leafNode prefetches batches from its source in a goroutine
+type leafNode struct {
+	src     InodeSource
+	batches chan leafBatch
+	stop    chan struct{}
+	exited  chan struct{}
+	once    sync.Once
+	buf     []uint64
+	last    uint64
+	popped  bool
+	done    bool
+}
+
+func (l *leafNode) start() {
+	l.batches = make(chan leafBatch, 2)
+	l.stop = make(chan struct{})
+	l.exited = make(chan struct{})
+	go func() {
+		defer close(l.exited)
+		defer close(l.batches)
+		for {
+			select {
+			case <-l.stop:
+				return
+			default:
+			}
+			inodes, err := l.src.Next()
+			if err == nil && len(inodes) == 0 {
+				return
+			}
+			select {
+			case l.batches <- leafBatch{inodes: inodes, err: err}:
+			case <-l.stop:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+}
+
+func (l *leafNode) peek() (uint64, bool, error) {
+	if l.batches == nil && !l.done {
+		l.start()
+	}
+	for {
+		for len(l.buf) > 0 && l.popped && l.buf[0] <= l.last {
+			// sources may return duplicates across batches
+			l.buf = l.buf[1:]
+		}
+		if len(l.buf) > 0 {
+			return l.buf[0], true, nil
+		}
+		if l.done {
+			return 0, false, nil
+		}
+		b, ok := <-l.batches
+		if !ok {
+			l.done = true
+			return 0, false, nil
+		}
+		if b.err != nil {
+			l.done = true
+			return 0, false, b.err
+		}
+		l.buf = b.inodes
+	}
+}
+
+func (l *leafNode) pop() {
+	if len(l.buf) > 0 {
+		l.last = l.buf[0]
+		l.popped = true
+		l.buf = l.buf[1:]
+	}
+}
+
+// close stops the goroutine and waits for it to exit so that the source
+// isn't used after close returns
+func (l *leafNode) close() {
+	l.once.Do(func() {
+		if l.stop != nil {
+			close(l.stop)
+			<-l.exited
+		}
+		l.done = true
+	})
+}
+
+type andNode struct {
+	children []searchNode
+}
+
+func (n *andNode) peek() (uint64, bool, error) {
+	if len(n.children) == 0 {
+		return 0, false, nil
+	}
+	for {
+		var max uint64
+		for i, c := range n.children {
+			ino, ok, err := c.peek()
+			if err != nil || !ok {
+				return 0, false, err
+			}
+			if i == 0 || ino > max {
+				max = ino
+			}
+		}
+
+		matched := true
+		for _, c := range n.children {
+			ino, _, _ := c.peek()
+			if ino < max {
+				c.pop()
+				matched = false
+			}
+		}
+		if matched {
+			return max, true, nil
+		}
+	}
+}
+
+func (n *andNode) pop() {
+	for _, c := range n.children {
+		c.pop()
+	}
+}
+
+func (n *andNode) close() {
+	for _, c := range n.children {
+		c.close()
+	}
+}
+
+type orNode struct {
+	children []searchNode
+}
+
+func (n *orNode) peek() (uint64, bool, error) {
+	var min uint64
+	found := false
+	for _, c := range n.children {
+		ino, ok, err := c.peek()
+		if err != nil {
+			return 0, false, err
+		}
+		if ok && (!found || ino < min) {
+			min = ino
+			found = true
+		}
+	}
+	return min, found, nil
+}
+
+func (n *orNode) pop() {
+	min, ok, _ := n.peek()
+	if !ok {
+		return
+	}
+	for _, c := range n.children {
+		ino, ok, _ := c.peek()
+		if ok && ino == min {
+			c.pop()
+		}
+	}
+}
+
+func (n *orNode) close() {
+	for _, c := range n.children {
+		c.close()
+	}
+}
+
+type diffNode struct {
+	a searchNode
+	b searchNode
+}
+
+func (n *diffNode) peek() (uint64, bool, error) {
+	for {
+		ino, ok, err := n.a.peek()
+		if err != nil || !ok {
+			return 0, false, err
+		}
+
+		for {
+			bino, bok, err := n.b.peek()
+			if err != nil {
+				return 0, false, err
+			}
+			if !bok || bino > ino {
+				return ino, true, nil
+			}
+			if bino == ino {
+				n.a.pop()
+				break
+			}
+			n.b.pop()
+		}
+	}
+}
+
+func (n *diffNode) pop() {
+	n.a.pop()
+}
+
+func (n *diffNode) close() {
+	n.a.close()
+	n.b.close()
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sliceSource returns inodes in batches, repeating the last inode of
+// each batch at the start of the next like xattr searches may
+type sliceSource struct {
+	inodes []uint64
+	batch  int
+	delay  time.Duration
+	err    error
+
+	last   uint64
+	calls  int32
+	inNext int32
+}
+
+func (s *sliceSource) Next() ([]uint64, error) {
+	atomic.AddInt32(&s.inNext, 1)
+	defer atomic.AddInt32(&s.inNext, -1)
+	n := atomic.AddInt32(&s.calls, 1)
+
+	if s.delay > 0 {
+		time.Sleep(s.delay)
+	}
+	if len(s.inodes) == 0 {
+		return nil, s.err
+	}
+
+	l := s.batch
+	if l <= 0 || l > len(s.inodes) {
+		l = len(s.inodes)
+	}
+	b := append([]uint64(nil), s.inodes[:l]...)
+	s.inodes = s.inodes[l:]
+	if n > 1 {
+		b = append([]uint64{s.last}, b...)
+	}
+	s.last = b[len(b)-1]
+	return b, nil
+}
+
+func src(inodes ...uint64) *SearchExpression {
+	return SearchSource(&sliceSource{inodes: inodes, batch: 2})
+}
+
+func readAll(t *testing.T, e *SearchExpression) []uint64 {
+	t.Helper()
+	var all []uint64
+	for {
+		inodes, err := e.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(inodes) == 0 {
+			return all
+		}
+		all = append(all, inodes...)
+	}
+}
+
+func TestSearchMerge(t *testing.T) {
+	tests := []struct {
+		name string
+		expr func() *SearchExpression
+		want []uint64
+	}{
+		{"source", func() *SearchExpression {
+			return src(1, 3, 5, 7, 9)
+		}, []uint64{1, 3, 5, 7, 9}},
+		{"empty source", func() *SearchExpression {
+			return src()
+		}, nil},
+		{"and", func() *SearchExpression {
+			return SearchAnd(src(1, 2, 3, 5, 8, 13), src(2, 3, 5, 7, 11, 13))
+		}, []uint64{2, 3, 5, 13}},
+		{"and with empty", func() *SearchExpression {
+			return SearchAnd(src(1, 2, 3), src())
+		}, nil},
+		{"and of nothing", func() *SearchExpression {
+			return SearchAnd()
+		}, nil},
+		{"or", func() *SearchExpression {
+			return SearchOr(src(1, 4, 9), src(2, 4, 8), src(3, 9, 27))
+		}, []uint64{1, 2, 3, 4, 8, 9, 27}},
+		{"or with empty", func() *SearchExpression {
+			return SearchOr(src(), src(5, 6))
+		}, []uint64{5, 6}},
+		{"difference", func() *SearchExpression {
+			return SearchDifference(src(1, 2, 3, 4, 5, 6), src(2, 4, 7))
+		}, []uint64{1, 3, 5, 6}},
+		{"difference of empty", func() *SearchExpression {
+			return SearchDifference(src(), src(1, 2))
+		}, nil},
+		{"nested", func() *SearchExpression {
+			return SearchDifference(
+				SearchAnd(SearchOr(src(1, 3, 5), src(2, 4, 6)), src(2, 3, 4, 5, 10)),
+				src(4))
+		}, []uint64{2, 3, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, batch := range []int{1, 2, searchBatch} {
+				e := tt.expr()
+				e.SetBatchSize(batch)
+				got := readAll(t, e)
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("batch %v: got %v, want %v", batch, got, tt.want)
+				}
+				e.Close()
+			}
+		})
+	}
+}
+
+func TestSearchError(t *testing.T) {
+	errTest := errors.New("test error")
+	e := SearchOr(src(1, 2, 3),
+		SearchSource(&sliceSource{inodes: []uint64{4}, err: errTest}))
+
+	var err error
+	for i := 0; i < 10 && err == nil; i++ {
+		_, err = e.Next()
+	}
+	if !errors.Is(err, errTest) {
+		t.Fatalf("Next() = %v, want %v", err, errTest)
+	}
+	e.Close()
+}
+
+func TestSearchEarlyClose(t *testing.T) {
+	inodes := make([]uint64, 10000)
+	for i := range inodes {
+		inodes[i] = uint64(i + 1)
+	}
+	a := &sliceSource{inodes: inodes, batch: 10, delay: time.Millisecond}
+	b := &sliceSource{inodes: append([]uint64(nil), inodes...), batch: 10,
+		delay: time.Millisecond}
+
+	e := SearchAnd(SearchSource(a), SearchSource(b))
+	e.SetBatchSize(5)
+	got, err := e.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []uint64{1, 2, 3, 4, 5}) {
+		t.Fatalf("Next() = %v", got)
+	}
+
+	e.Close()
+	for _, s := range []*sliceSource{a, b} {
+		if n := atomic.LoadInt32(&s.inNext); n != 0 {
+			t.Errorf("%v reads in progress after Close", n)
+		}
+	}
+	calls := atomic.LoadInt32(&a.calls) + atomic.LoadInt32(&b.calls)
+	time.Sleep(10 * time.Millisecond)
+	if n := atomic.LoadInt32(&a.calls) + atomic.LoadInt32(&b.calls); n != calls {
+		t.Errorf("%v reads after Close", n-calls)
+	}
+	e.Close()
+}
@@ -0,0 +1,285 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// SoftLimit is a warning threshold and grace period for a quota rule
+type SoftLimit struct {
+	// Rule is the quota rule the soft limit applies to
+	Rule QuotaRule
+	// Threshold is the percent of the rule limit that starts the grace
+	// period
+	Threshold float64
+	// Grace is how long usage may stay above the threshold
+	Grace time.Duration
+}
+
+type softLimitJSON struct {
+	Rule      string  `json:"rule"`
+	Threshold float64 `json:"threshold"`
+	Grace     string  `json:"grace"`
+}
+
+// ParseSoftLimits parses a JSON list of soft limits of the form
+// [{"rule": "<quota rule text>", "threshold": 90, "grace": "72h"}]
+func ParseSoftLimits(b []byte) ([]SoftLimit, error) {
+	var js []softLimitJSON
+	err := json.Unmarshal(b, &js)
+	if err != nil {
+		return nil, fmt.Errorf("parse soft limits: %v", err)
+	}
+
+	limits := make([]SoftLimit, len(js))
+	for i, j := range js {
+		limits[i].Rule, err = ParseQuotaRule(j.Rule)
+		if err != nil {
+			return nil, err
+		}
+		if j.Threshold <= 0 {
+			return nil, fmt.Errorf("rule %q: threshold %v must be > 0",
+				j.Rule, j.Threshold)
+		}
+		limits[i].Threshold = j.Threshold
+		if j.Grace != "" {
+			limits[i].Grace, err = time.ParseDuration(j.Grace)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: parse grace %q: %v",
+					j.Rule, j.Grace, err)
+			}
+		}
+	}
+	return limits, nil
+}
+
+// AlertKind is the type of soft limit alert
+type AlertKind int
+
+const (
+	// AlertWarning is sent when usage first crosses the threshold
+	AlertWarning AlertKind = iota
+	// AlertGraceExpired is sent once when usage has stayed above the
+	// threshold longer than the grace period
+	AlertGraceExpired
+	// AlertCleared is sent when usage drops below the threshold
+	AlertCleared
+)
+
+func (a AlertKind) String() string {
+	switch a {
+	case AlertWarning:
+		return "warning"
+	case AlertGraceExpired:
+		return "grace expired"
+	case AlertCleared:
+		return "cleared"
+	default:
+		return "unknown"
+	}
+}
+
+// AlertEvent is a soft limit state change
+type AlertEvent struct {
+	Kind AlertKind
+	// Usage is the usage that caused the event, Usage is 0 for cleared
+	// events of totals that no longer exist
+	Usage QuotaUsage
+	// Since is when the threshold was first crossed
+	Since time.Time
+}
+
+func (e AlertEvent) String() string {
+	return fmt.Sprintf("%v since %v: %v", e.Kind,
+		e.Since.UTC().Format(time.RFC3339), e.Usage)
+}
+
+// SoftLimitEntry is the persisted state of a crossed threshold, Rule is
+// the text of the rule when the threshold was last checked
+type SoftLimitEntry struct {
+	Rule    string    `json:"rule"`
+	ID      [3]uint64 `json:"id"`
+	Since   time.Time `json:"since"`
+	Expired bool      `json:"expired"`
+}
+
+// SoftLimitState holds when soft limit thresholds were first crossed
+type SoftLimitState struct {
+	Crossed map[string]*SoftLimitEntry `json:"crossed"`
+}
+
+// LoadSoftLimitState reads the state from path, a missing file is an
+// empty state
+func LoadSoftLimitState(path string) (*SoftLimitState, error) {
+	s := &SoftLimitState{Crossed: make(map[string]*SoftLimitEntry)}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, s)
+	if err != nil {
+		return nil, fmt.Errorf("parse %q: %v", path, err)
+	}
+	if s.Crossed == nil {
+		s.Crossed = make(map[string]*SoftLimitEntry)
+	}
+
+	// rekey entries in case they were saved with other keys
+	crossed := make(map[string]*SoftLimitEntry, len(s.Crossed))
+	for key, e := range s.Crossed {
+		q, err := ParseQuotaRule(e.Rule)
+		if err == nil {
+			key = softLimitKey(q, e.ID)
+		}
+		crossed[key] = e
+	}
+	s.Crossed = crossed
+	return s, nil
+}
+
+// Save atomically writes the state to path
+func (s *SoftLimitState) Save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".softlimit-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	_, err = tmp.Write(b)
+	if err != nil {
+		return err
+	}
+	err = tmp.Sync()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// softLimitKey identifies the totals of a rule without its limit so that
+// changing the limit of a rule keeps its grace period
+func softLimitKey(q QuotaRule, id [3]uint64) string {
+	return fmt.Sprintf("%v %v.%v.%v", FormatQuotaRule(quotaIdentity(q)),
+		id[0], id[1], id[2])
+}
+
+// SoftLimitChecker computes usage for soft limits and tracks threshold
+// crossings
+type SoftLimitChecker struct {
+	limits []SoftLimit
+	state  *SoftLimitState
+}
+
+// NewSoftLimitChecker creates a checker for the limits, a nil state
+// starts empty
+func NewSoftLimitChecker(limits []SoftLimit, state *SoftLimitState) *SoftLimitChecker {
+	if state == nil {
+		state = &SoftLimitState{}
+	}
+	if state.Crossed == nil {
+		state.Crossed = make(map[string]*SoftLimitEntry)
+	}
+	return &SoftLimitChecker{
+		limits: limits,
+		state:  state,
+	}
+}
+
+// State returns the checker state for persisting
+func (c *SoftLimitChecker) State() *SoftLimitState {
+	return c.state
+}
+
+// Check reads the current usage for all soft limits and returns the
+// alerts for threshold state changes since the last check
+func (c *SoftLimitChecker) Check(f *os.File, now time.Time) ([]AlertEvent, error) {
+	var events []AlertEvent
+	seen := make(map[string]bool)
+
+	for _, l := range c.limits {
+		usage, err := quotaRuleUsage(f, l.Rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %v", FormatQuotaRule(l.Rule), err)
+		}
+
+		rule := FormatQuotaRule(l.Rule)
+		for _, u := range usage {
+			key := softLimitKey(l.Rule, u.ID)
+			seen[key] = true
+			e, crossed := c.state.Crossed[key]
+			over := u.Percent() >= l.Threshold
+			if crossed {
+				e.Rule = rule
+			}
+
+			switch {
+			case over && !crossed:
+				c.state.Crossed[key] = &SoftLimitEntry{
+					Rule:  rule,
+					ID:    u.ID,
+					Since: now,
+				}
+				events = append(events, AlertEvent{
+					Kind:  AlertWarning,
+					Usage: u,
+					Since: now,
+				})
+			case over && !e.Expired && now.Sub(e.Since) >= l.Grace:
+				e.Expired = true
+				events = append(events, AlertEvent{
+					Kind:  AlertGraceExpired,
+					Usage: u,
+					Since: e.Since,
+				})
+			case !over && crossed:
+				delete(c.state.Crossed, key)
+				events = append(events, AlertEvent{
+					Kind:  AlertCleared,
+					Usage: u,
+					Since: e.Since,
+				})
+			}
+		}
+	}
+
+	// totals that are gone or limits that were removed are cleared
+	var gone []string
+	for key := range c.state.Crossed {
+		if !seen[key] {
+			gone = append(gone, key)
+		}
+	}
+	sort.Strings(gone)
+	for _, key := range gone {
+		e := c.state.Crossed[key]
+		delete(c.state.Crossed, key)
+		rule, _ := ParseQuotaRule(e.Rule)
+		events = append(events, AlertEvent{
+			Kind:  AlertCleared,
+			Usage: QuotaUsage{Rule: rule, ID: e.ID},
+			Since: e.Since,
+		})
+	}
+
+	return events, nil
+}
@@ -55,6 +55,50 @@ func scoutfsctl(f *os.File, cmd int, ptr unsafe.Pointer) (int, error) {
 	return int(count), err
 }
 
+func fgetxattr(f *os.File, name string, b []byte) (int, error) {
+	n, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return 0, err
+	}
+	var p unsafe.Pointer
+	if len(b) > 0 {
+		p = unsafe.Pointer(&b[0])
+	}
+	size, _, e1 := syscall.Syscall6(syscall.SYS_FGETXATTR, uintptr(f.Fd()), uintptr(unsafe.Pointer(n)), uintptr(p), uintptr(len(b)), 0, 0)
+	if e1 != 0 {
+		return 0, errnoErr(e1)
+	}
+	return int(size), nil
+}
+
+func fsetxattr(f *os.File, name string, b []byte, flags int) error {
+	n, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return err
+	}
+	var p unsafe.Pointer
+	if len(b) > 0 {
+		p = unsafe.Pointer(&b[0])
+	}
+	_, _, e1 := syscall.Syscall6(syscall.SYS_FSETXATTR, uintptr(f.Fd()), uintptr(unsafe.Pointer(n)), uintptr(p), uintptr(len(b)), uintptr(flags), 0)
+	if e1 != 0 {
+		return errnoErr(e1)
+	}
+	return nil
+}
+
+func fremovexattr(f *os.File, name string) error {
+	n, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return err
+	}
+	_, _, e1 := syscall.Syscall(syscall.SYS_FREMOVEXATTR, uintptr(f.Fd()), uintptr(unsafe.Pointer(n)), 0)
+	if e1 != 0 {
+		return errnoErr(e1)
+	}
+	return nil
+}
+
 // Do the interface allocations only once for common
 // Errno values.
 var (
@@ -0,0 +1,260 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// sysfsAttrMax is the most read from a single attribute file
+	sysfsAttrMax = 64 * 1024
+
+	sysfsMountOptions = "mount_options"
+	sysfsCounters     = "counters"
+)
+
+// Sysfs reads the per-mount attributes scoutfs publishes in sysfs
+type Sysfs struct {
+	root string
+}
+
+// SysOption sets various options for NewSysfs
+type SysOption func(*Sysfs)
+
+// WithSysfsRoot sets the directory holding the per-mount directories,
+// such as a copy of /sys/fs/scoutfs for testing
+func WithSysfsRoot(dir string) SysOption {
+	return func(s *Sysfs) {
+		s.root = dir
+	}
+}
+
+// NewSysfs creates a Sysfs reader for /sys/fs/scoutfs
+func NewSysfs(opts ...SysOption) *Sysfs {
+	s := &Sysfs{root: sysscoutfs}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Mounts returns the short ids of the mounts with sysfs directories
+func (s *Sysfs) Mounts() ([]string, error) {
+	ents, err := os.ReadDir(s.root)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, e := range ents {
+		if e.IsDir() || e.Type()&fs.ModeSymlink != 0 {
+			ids = append(ids, e.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// SysfsInfo holds the sysfs attributes of a mount
+type SysfsInfo struct {
+	ShortID string
+	// Attrs holds every readable attribute by its path within the mount
+	// directory, such as "quorum/status", with surrounding space trimmed
+	Attrs map[string]string
+	// MountOptions holds the attributes in mount_options
+	MountOptions map[string]string
+	// Counters holds the attributes in counters with numeric values
+	Counters map[string]uint64
+	// QuorumStatus holds the lines of quorum/status by their first field
+	QuorumStatus map[string]string
+	// Quorum is parsed from quorum/status, zero if the mount has none
+	Quorum QuorumInfo
+}
+
+// Get returns the attribute at name within the mount directory
+func (i SysfsInfo) Get(name string) (string, bool) {
+	v, ok := i.Attrs[name]
+	return v, ok
+}
+
+// Uint returns the attribute at name parsed as an unsigned integer
+func (i SysfsInfo) Uint(name string) (uint64, error) {
+	v, ok := i.Attrs[name]
+	if !ok {
+		return 0, fmt.Errorf("attribute %q: %w", name, os.ErrNotExist)
+	}
+	n, err := strconv.ParseUint(v, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("attribute %q: %v", name, err)
+	}
+	return n, nil
+}
+
+// InfoFor returns the sysfs attributes for the mount of file handle
+func (s *Sysfs) InfoFor(f *os.File) (SysfsInfo, error) {
+	id, err := GetIDs(f)
+	if err != nil {
+		return SysfsInfo{}, fmt.Errorf("error GetIDs: %v", err)
+	}
+	return s.Info(id.ShortID)
+}
+
+// Info returns the sysfs attributes of the mount with the short id.
+// Attributes that can't be read, such as write only attributes, are
+// skipped and unknown attributes are kept in Attrs.
+func (s *Sysfs) Info(shortID string) (SysfsInfo, error) {
+	dir := filepath.Join(s.root, shortID)
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return SysfsInfo{}, err
+	}
+	if !fi.IsDir() {
+		return SysfsInfo{}, fmt.Errorf("%q is not a directory", dir)
+	}
+
+	info := SysfsInfo{
+		ShortID:      shortID,
+		Attrs:        make(map[string]string),
+		MountOptions: make(map[string]string),
+		Counters:     make(map[string]uint64),
+		QuorumStatus: make(map[string]string),
+	}
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			// unreadable directories are skipped
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		v, ok := readSysfsAttr(path)
+		if !ok {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info.Attrs[filepath.ToSlash(rel)] = v
+		return nil
+	})
+	if err != nil {
+		return SysfsInfo{}, err
+	}
+
+	for name, v := range info.Attrs {
+		parent, base := filepath.Split(name)
+		switch strings.TrimSuffix(parent, "/") {
+		case sysfsMountOptions:
+			info.MountOptions[base] = v
+		case sysfsCounters:
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err == nil {
+				info.Counters[base] = n
+			}
+		}
+	}
+
+	if status, ok := info.Attrs[statusfile]; ok {
+		info.Quorum, err = parseQuorumStatus(status, info.QuorumStatus)
+		if err != nil {
+			return SysfsInfo{}, fmt.Errorf("parse %q: %v",
+				filepath.Join(dir, statusfile), err)
+		}
+	}
+
+	return info, nil
+}
+
+// readSysfsAttr returns the trimmed contents of an attribute file, false
+// if it can't be read
+func readSysfsAttr(path string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(io.LimitReader(f, sysfsAttrMax))
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(b)), true
+}
+
+// Quorum returns the quorum info from quorum/status of the mount with
+// the short id
+func (s *Sysfs) Quorum(shortID string) (QuorumInfo, error) {
+	path := filepath.Join(s.root, shortID, statusfile)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return QuorumInfo{}, fmt.Errorf("open %q: %v", path, err)
+	}
+
+	qi, err := parseQuorumStatus(string(b), make(map[string]string))
+	if err != nil {
+		return QuorumInfo{}, fmt.Errorf("parse %q: %v", path, err)
+	}
+	return qi, nil
+}
+
+// parseQuorumStatus records the status lines by their first field and
+// returns the quorum info from the known fields
+func parseQuorumStatus(status string, lines map[string]string) (QuorumInfo, error) {
+	var qi QuorumInfo
+	var err error
+	status = strings.TrimSpace(status)
+	if status == "" {
+		return qi, nil
+	}
+	for _, line := range strings.Split(status, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return QuorumInfo{}, fmt.Errorf("parse line %q", line)
+		}
+		lines[fields[0]] = strings.Join(fields[1:], " ")
+
+		switch fields[0] {
+		case "quorum_slot_nr":
+			qi.Slot, err = strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return QuorumInfo{}, fmt.Errorf("parse quorum_slot_nr %q: %v",
+					fields[1], err)
+			}
+		case "term":
+			qi.Term, err = strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return QuorumInfo{}, fmt.Errorf("parse term %q: %v",
+					fields[1], err)
+			}
+		case "role":
+			if len(fields) < 3 {
+				return QuorumInfo{}, fmt.Errorf("parse line %q", line)
+			}
+			qi.Role = fields[2]
+		}
+	}
+	return qi, nil
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeSysfsTree(t *testing.T, root, shortID string, attrs map[string]string) {
+	t.Helper()
+	for name, v := range attrs {
+		path := filepath.Join(root, shortID, name)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(v), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSysfsInfo(t *testing.T) {
+	root := t.TempDir()
+	writeSysfsTree(t, root, "f.1a2b3c.r.4d5e6f", map[string]string{
+		"mount_options/quorum_slot_nr": "2\n",
+		"mount_options/metadev_path":   "/dev/meta\n",
+		"counters/lock_grant":          "12\n",
+		"counters/not_a_number":        "abc\n",
+		"quorum/status":                "quorum_slot_nr 2\nterm 7\nrole 3 (leader)\n",
+		"data_device_maj_min":          "8:16\n",
+	})
+	writeSysfsTree(t, root, "f.1a2b3c.r.000000", map[string]string{
+		"quorum/status": "",
+	})
+
+	s := NewSysfs(WithSysfsRoot(root))
+
+	ids, err := s.Mounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"f.1a2b3c.r.000000", "f.1a2b3c.r.4d5e6f"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Fatalf("Mounts() = %v, want %v", ids, want)
+	}
+
+	info, err := s.Info("f.1a2b3c.r.4d5e6f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := info.Get("data_device_maj_min"); v != "8:16" {
+		t.Errorf("data_device_maj_min = %q, want %q", v, "8:16")
+	}
+	if n, err := info.Uint("mount_options/quorum_slot_nr"); err != nil || n != 2 {
+		t.Errorf("Uint(quorum_slot_nr) = %v, %v, want 2", n, err)
+	}
+	if _, err := info.Uint("missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Uint(missing) = %v, want not exist", err)
+	}
+	wantOpts := map[string]string{"quorum_slot_nr": "2", "metadev_path": "/dev/meta"}
+	if !reflect.DeepEqual(info.MountOptions, wantOpts) {
+		t.Errorf("MountOptions = %v, want %v", info.MountOptions, wantOpts)
+	}
+	wantCounters := map[string]uint64{"lock_grant": 12}
+	if !reflect.DeepEqual(info.Counters, wantCounters) {
+		t.Errorf("Counters = %v, want %v", info.Counters, wantCounters)
+	}
+	wantQuorum := QuorumInfo{Slot: 2, Term: 7, Role: "(leader)"}
+	if info.Quorum != wantQuorum || !info.Quorum.IsLeader() {
+		t.Errorf("Quorum = %+v, want %+v", info.Quorum, wantQuorum)
+	}
+	if info.QuorumStatus["role"] != "3 (leader)" {
+		t.Errorf("QuorumStatus[role] = %q, want %q",
+			info.QuorumStatus["role"], "3 (leader)")
+	}
+
+	qi, err := s.Quorum("f.1a2b3c.r.4d5e6f")
+	if err != nil || qi != wantQuorum {
+		t.Errorf("Quorum() = %+v, %v, want %+v", qi, err, wantQuorum)
+	}
+	qi, err = s.Quorum("f.1a2b3c.r.000000")
+	if err != nil || qi != (QuorumInfo{}) {
+		t.Errorf("Quorum() of empty status = %+v, %v, want zero", qi, err)
+	}
+
+	_, err = s.Info("f.000000.r.000000")
+	if !os.IsNotExist(err) {
+		t.Errorf("Info() of missing mount = %v, want not exist", err)
+	}
+}
+
+func TestParseQuorumStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  string
+		want    QuorumInfo
+		wantErr bool
+	}{
+		{"empty", "", QuorumInfo{}, false},
+		{"follower", "quorum_slot_nr 0\nterm 3\nrole 1 (follower)\n",
+			QuorumInfo{Slot: 0, Term: 3, Role: "(follower)"}, false},
+		{"unknown fields", "quorum_slot_nr 1\nserver_event 2 elected\n",
+			QuorumInfo{Slot: 1}, false},
+		{"short line", "quorum_slot_nr\n", QuorumInfo{}, true},
+		{"bad slot", "quorum_slot_nr x\n", QuorumInfo{}, true},
+		{"bad term", "term -\n", QuorumInfo{}, true},
+		{"short role", "role 1\n", QuorumInfo{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseQuorumStatus(tt.status, make(map[string]string))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseQuorumStatus() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseQuorumStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSysfsInfoBadQuorumStatus(t *testing.T) {
+	root := t.TempDir()
+	writeSysfsTree(t, root, "f.1a2b3c.r.4d5e6f", map[string]string{
+		"quorum/status": "term seven\n",
+	})
+
+	s := NewSysfs(WithSysfsRoot(root))
+	if _, err := s.Info("f.1a2b3c.r.4d5e6f"); err == nil {
+		t.Error("Info() with bad quorum status succeeded")
+	}
+	if _, err := s.Quorum("f.1a2b3c.r.4d5e6f"); err == nil {
+		t.Error("Quorum() with bad quorum status succeeded")
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// WalkNode is a directory entry in a WalkTree
+type WalkNode struct {
+	// Ino is the inode the entry refers to
+	Ino uint64
+	// Parent is the inode of the directory containing the entry
+	Parent uint64
+	// Type matches the DT_ enum values in readdir(3)
+	Type uint8
+	Name string
+}
+
+// WalkTree is the directory tree of a filesystem reconstructed from the
+// parents of every inode
+type WalkTree struct {
+	children map[uint64][]WalkNode
+	// first entry referring to each inode
+	links map[uint64]WalkNode
+}
+
+// Len returns the number of inodes in the tree, including the root
+func (t *WalkTree) Len() int {
+	return len(t.links) + 1
+}
+
+// Children returns the entries in directory ino
+func (t *WalkTree) Children(ino uint64) []WalkNode {
+	return t.children[ino]
+}
+
+// Path returns a path of ino relative to the filesystem root
+func (t *WalkTree) Path(ino uint64) (string, bool) {
+	var names []string
+	for ino != rootIno {
+		n, ok := t.links[ino]
+		if !ok {
+			return "", false
+		}
+		names = append(names, n.Name)
+		ino = n.Parent
+	}
+
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return strings.Join(names, "/"), true
+}
+
+// Lookup returns the inode at path relative to the filesystem root
+func (t *WalkTree) Lookup(path string) (uint64, bool) {
+	ino := uint64(rootIno)
+	for _, name := range strings.Split(path, "/") {
+		if name == "" || name == "." {
+			continue
+		}
+		found := false
+		for _, n := range t.children[ino] {
+			if n.Name == name {
+				ino = n.Ino
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return ino, true
+}
+
+// WalkEntry is an inode found by a Walker
+type WalkEntry struct {
+	// Path is relative to the filesystem root
+	Path string
+	Ino  uint64
+	Info os.FileInfo
+	// Stat is zero for inodes that can't be opened, such as symlinks
+	Stat Stat
+}
+
+// Walker enumerates inodes with the meta_seq index and resolves their
+// parents to reconstruct the directory tree, which is much faster than
+// reading every directory.  Inodes with multiple links are found at each
+// of their paths.
+type Walker struct {
+	dirfd   *os.File
+	workers int
+	batch   uint32
+}
+
+// WKOption sets various options for NewWalker
+type WKOption func(*Walker)
+
+// WithWalkerWorkers sets the number of parallel parent lookups and stats
+func WithWalkerWorkers(n int) WKOption {
+	return func(w *Walker) {
+		w.workers = n
+	}
+}
+
+// WithWalkerBatchSize sets the number of inodes read per meta_seq query
+func WithWalkerBatchSize(size uint32) WKOption {
+	return func(w *Walker) {
+		w.batch = size
+	}
+}
+
+// NewWalker creates a Walker for the filesystem of dirfd
+// An open file within scoutfs is supplied for ioctls
+// (usually just the base mount point directory)
+func NewWalker(dirfd *os.File, opts ...WKOption) *Walker {
+	w := &Walker{
+		dirfd:   dirfd,
+		workers: 8,
+		batch:   1024,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.workers < 1 {
+		w.workers = 1
+	}
+
+	return w
+}
+
+// walkErr records the first error and stops the walk
+type walkErr struct {
+	once sync.Once
+	stop chan struct{}
+	err  error
+}
+
+func newWalkErr() *walkErr {
+	return &walkErr{stop: make(chan struct{})}
+}
+
+func (e *walkErr) set(err error) {
+	e.once.Do(func() {
+		e.err = err
+		close(e.stop)
+	})
+}
+
+type inodeLinks struct {
+	ino     uint64
+	parents []Parent
+}
+
+// Tree reads the parents of every inode and returns the directory tree.
+// Inodes removed during the walk and inodes without a path are left out.
+func (w *Walker) Tree() (*WalkTree, error) {
+	werr := newWalkErr()
+	inos := make(chan []uint64, w.workers)
+	out := make(chan []inodeLinks, w.workers)
+
+	go func() {
+		defer close(inos)
+		min := InodesEntry{}
+		max := InodesEntry{Major: max64, Minor: max32, Ino: max64}
+		q := NewQuery(w.dirfd, ByMSeq(min, max), WithBatchSize(w.batch))
+		for {
+			ents, err := q.Next()
+			if err != nil {
+				werr.set(fmt.Errorf("query meta_seq: %v", err))
+				return
+			}
+			if len(ents) == 0 {
+				return
+			}
+			batch := make([]uint64, len(ents))
+			for i, e := range ents {
+				batch[i] = e.Ino
+			}
+			select {
+			case inos <- batch:
+			case <-werr.stop:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(w.workers)
+	for i := 0; i < w.workers; i++ {
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, linksBufsize)
+			for batch := range inos {
+				var res []inodeLinks
+				for _, ino := range batch {
+					if ino == rootIno {
+						continue
+					}
+					parents, err := GetParents(w.dirfd, ino, buf)
+					if err == syscall.ENOENT || err == syscall.ESTALE {
+						continue
+					}
+					if err != nil {
+						werr.set(fmt.Errorf("parents of inode %v: %v", ino, err))
+						return
+					}
+					if len(parents) > 0 {
+						res = append(res, inodeLinks{ino: ino, parents: parents})
+					}
+				}
+				select {
+				case out <- res:
+				case <-werr.stop:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	t := &WalkTree{
+		children: make(map[uint64][]WalkNode),
+		links:    make(map[uint64]WalkNode),
+	}
+	for res := range out {
+		for _, l := range res {
+			for i, p := range l.parents {
+				n := WalkNode{Ino: l.ino, Parent: p.Ino, Type: p.Type, Name: p.Ent}
+				t.children[p.Ino] = append(t.children[p.Ino], n)
+				if i == 0 {
+					t.links[l.ino] = n
+				}
+			}
+		}
+	}
+
+	if werr.err != nil {
+		return nil, werr.err
+	}
+	return t, nil
+}
+
+// Walk builds the tree and calls fn for root and every inode below it.
+// See WalkTree.
+func (w *Walker) Walk(root string, fn func(WalkEntry) error) error {
+	t, err := w.Tree()
+	if err != nil {
+		return err
+	}
+	return w.WalkTree(t, root, fn)
+}
+
+type treeJob struct {
+	path string
+	ino  uint64
+}
+
+// WalkTree calls fn for root, a path relative to the filesystem root,
+// and every inode below it in the tree.  Inodes are stat'd in parallel
+// and fn is called from a single goroutine in no particular order.
+// Inodes removed since the tree was built are skipped.  The first error
+// returned by fn stops the walk and is returned.
+func (w *Walker) WalkTree(t *WalkTree, root string, fn func(WalkEntry) error) error {
+	root = strings.Trim(root, "/")
+	rino, ok := t.Lookup(root)
+	if !ok {
+		return fmt.Errorf("%q not found: %w", root, os.ErrNotExist)
+	}
+
+	werr := newWalkErr()
+	jobs := make(chan treeJob, 1024)
+	out := make(chan WalkEntry, 1024)
+
+	go func() {
+		defer close(jobs)
+		stack := []treeJob{{path: root, ino: rino}}
+		for len(stack) > 0 {
+			j := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			select {
+			case jobs <- j:
+			case <-werr.stop:
+				return
+			}
+			for _, n := range t.children[j.ino] {
+				p := n.Name
+				if j.path != "" {
+					p = j.path + "/" + n.Name
+				}
+				stack = append(stack, treeJob{path: p, ino: n.Ino})
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(w.workers)
+	for i := 0; i < w.workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				e, err := w.statInode(j)
+				if err == syscall.ENOENT || err == syscall.ESTALE || os.IsNotExist(err) {
+					continue
+				}
+				if err != nil {
+					werr.set(fmt.Errorf("stat %q: %v", j.path, err))
+					return
+				}
+				select {
+				case out <- e:
+				case <-werr.stop:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	for e := range out {
+		select {
+		case <-werr.stop:
+			// drain until the workers exit
+			continue
+		default:
+		}
+		err := fn(e)
+		if err != nil {
+			werr.set(err)
+		}
+	}
+
+	return werr.err
+}
+
+// statInode opens the inode by handle to stat it, inodes that can't be
+// opened are stat'd by path
+func (w *Walker) statInode(j treeJob) (WalkEntry, error) {
+	e := WalkEntry{Path: j.path, Ino: j.ino}
+	name := filepath.Join(w.dirfd.Name(), j.path)
+
+	fd, err := OpenByHandle(w.dirfd, j.ino,
+		syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_NOFOLLOW)
+	if err == syscall.ELOOP || err == syscall.ENXIO {
+		e.Info, err = os.Lstat(name)
+		return e, err
+	}
+	if err != nil {
+		return e, err
+	}
+
+	f := os.NewFile(fd, name)
+	defer f.Close()
+
+	e.Info, err = f.Stat()
+	if err != nil {
+		return e, err
+	}
+	e.Stat, err = FStatMore(f)
+	return e, err
+}
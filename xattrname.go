@@ -0,0 +1,222 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// xattrPrefix starts all scoutfs tagged xattr names
+	xattrPrefix = "scoutfs."
+	// XattrNameMax is the longest xattr name
+	XattrNameMax = 255
+
+	tagHide   = "hide."
+	tagSearch = "srch."
+	tagTotl   = "totl."
+	tagIndex  = "indx."
+)
+
+// XattrName is a scoutfs xattr name with its tags.  Names are formatted as
+// scoutfs.[hide.][srch.][totl.|indx.]<name>[.<ids>] where totl names end in
+// three ids and indx names end in the major and minor.
+type XattrName struct {
+	// Hide hides the xattr from listxattr
+	Hide bool
+	// Search adds the xattr to the search index used by XattrQuery
+	Search bool
+	// Totl adds the xattr value to the totals read by ReadXattrTotals
+	Totl bool
+	// Index adds the inode to the index read by IndexSearch
+	Index bool
+	// Name is the name following the tags, without totl or indx ids
+	Name string
+	// TotlID is the totl id for Totl names
+	TotlID [3]uint64
+	// IndexMajor is the index major for Index names
+	IndexMajor uint8
+	// IndexMinor is the index minor for Index names
+	IndexMinor uint64
+}
+
+// HiddenXattrName returns a hidden xattr name
+func HiddenXattrName(name string) XattrName {
+	return XattrName{Hide: true, Name: name}
+}
+
+// SearchXattrName returns a searchable xattr name
+func SearchXattrName(name string) XattrName {
+	return XattrName{Search: true, Name: name}
+}
+
+// TotlXattrName returns a totl xattr name for the id
+func TotlXattrName(name string, id1, id2, id3 uint64) XattrName {
+	return XattrName{Totl: true, Name: name, TotlID: [3]uint64{id1, id2, id3}}
+}
+
+// IndexXattrName returns an indx xattr name for the major and minor
+func IndexXattrName(name string, major uint8, minor uint64) XattrName {
+	return XattrName{Index: true, Name: name, IndexMajor: major, IndexMinor: minor}
+}
+
+// WithHide returns the name with the hide tag
+func (x XattrName) WithHide() XattrName {
+	x.Hide = true
+	return x
+}
+
+// WithSearch returns the name with the srch tag
+func (x XattrName) WithSearch() XattrName {
+	x.Search = true
+	return x
+}
+
+// String returns the full xattr name
+func (x XattrName) String() string {
+	var b strings.Builder
+	b.WriteString(xattrPrefix)
+	if x.Hide {
+		b.WriteString(tagHide)
+	}
+	if x.Search {
+		b.WriteString(tagSearch)
+	}
+	if x.Totl {
+		b.WriteString(tagTotl)
+	}
+	if x.Index {
+		b.WriteString(tagIndex)
+	}
+
+	var ids []string
+	if x.Totl {
+		ids = append(ids, strconv.FormatUint(x.TotlID[0], 10),
+			strconv.FormatUint(x.TotlID[1], 10), strconv.FormatUint(x.TotlID[2], 10))
+	}
+	if x.Index {
+		ids = append(ids, strconv.FormatUint(uint64(x.IndexMajor), 10),
+			strconv.FormatUint(x.IndexMinor, 10))
+	}
+	if x.Name != "" {
+		b.WriteString(x.Name)
+		if len(ids) > 0 {
+			b.WriteByte('.')
+		}
+	}
+	b.WriteString(strings.Join(ids, "."))
+
+	return b.String()
+}
+
+// Validate checks that the name is a valid scoutfs xattr name
+func (x XattrName) Validate() error {
+	if x.Totl && x.Index {
+		return fmt.Errorf("xattr can not be both totl and indx")
+	}
+	if !x.Totl && !x.Index && x.Name == "" {
+		return fmt.Errorf("xattr name is empty")
+	}
+	if strings.ContainsRune(x.Name, 0) {
+		return fmt.Errorf("xattr name contains nul")
+	}
+	if l := len(x.String()); l > XattrNameMax {
+		return fmt.Errorf("xattr name length %v exceeds %v", l, XattrNameMax)
+	}
+	return nil
+}
+
+// IsScoutfsXattr returns true if the xattr name is in the scoutfs namespace
+func IsScoutfsXattr(name string) bool {
+	return strings.HasPrefix(name, xattrPrefix)
+}
+
+// ParseXattrName parses a scoutfs xattr name, such as those returned by
+// ListXattrHidden, into its tags, name and ids
+func ParseXattrName(s string) (XattrName, error) {
+	if !IsScoutfsXattr(s) {
+		return XattrName{}, fmt.Errorf("not a scoutfs xattr %q", s)
+	}
+	if len(s) > XattrNameMax {
+		return XattrName{}, fmt.Errorf("xattr name length %v exceeds %v",
+			len(s), XattrNameMax)
+	}
+
+	var x XattrName
+	rest := s[len(xattrPrefix):]
+	tags := []struct {
+		tag  string
+		flag *bool
+	}{
+		{tagHide, &x.Hide},
+		{tagSearch, &x.Search},
+		{tagTotl, &x.Totl},
+		{tagIndex, &x.Index},
+	}
+	for matched := true; matched; {
+		matched = false
+		for _, t := range tags {
+			if !strings.HasPrefix(rest, t.tag) {
+				continue
+			}
+			if *t.flag {
+				return XattrName{}, fmt.Errorf("duplicate tag in xattr %q", s)
+			}
+			*t.flag = true
+			rest = rest[len(t.tag):]
+			matched = true
+			break
+		}
+	}
+
+	var nids int
+	switch {
+	case x.Totl && x.Index:
+		return XattrName{}, fmt.Errorf("xattr %q can not be both totl and indx", s)
+	case x.Totl:
+		nids = 3
+	case x.Index:
+		nids = 2
+	}
+
+	if nids == 0 {
+		if rest == "" {
+			return XattrName{}, fmt.Errorf("xattr %q has empty name", s)
+		}
+		x.Name = rest
+		return x, nil
+	}
+
+	parts := strings.Split(rest, ".")
+	if len(parts) < nids {
+		return XattrName{}, fmt.Errorf("xattr %q missing %v ids", s, nids)
+	}
+	ids := make([]uint64, nids)
+	for i, p := range parts[len(parts)-nids:] {
+		v, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return XattrName{}, fmt.Errorf("xattr %q invalid id %q", s, p)
+		}
+		ids[i] = v
+	}
+	x.Name = strings.Join(parts[:len(parts)-nids], ".")
+
+	if x.Totl {
+		copy(x.TotlID[:], ids)
+	} else {
+		if ids[0] > 255 {
+			return XattrName{}, fmt.Errorf("xattr %q index major %v exceeds 255",
+				s, ids[0])
+		}
+		x.IndexMajor = uint8(ids[0])
+		x.IndexMinor = ids[1]
+	}
+
+	return x, nil
+}
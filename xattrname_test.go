@@ -0,0 +1,121 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseXattrName(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want XattrName
+	}{
+		{"hide", "scoutfs.hide.data", HiddenXattrName("data")},
+		{"search", "scoutfs.srch.tag", SearchXattrName("tag")},
+		{"hide search", "scoutfs.hide.srch.tag",
+			XattrName{Hide: true, Search: true, Name: "tag"}},
+		{"untagged", "scoutfs.other", XattrName{Name: "other"}},
+		{"dotted name", "scoutfs.hide.a.b.c", HiddenXattrName("a.b.c")},
+		{"totl", "scoutfs.totl.bytes.1.2.3", TotlXattrName("bytes", 1, 2, 3)},
+		{"totl dotted name", "scoutfs.totl.a.b.18446744073709551615.0.7",
+			TotlXattrName("a.b", 18446744073709551615, 0, 7)},
+		{"totl no name", "scoutfs.totl.1.2.3", TotlXattrName("", 1, 2, 3)},
+		{"hidden totl", "scoutfs.hide.totl.n.1.2.3",
+			TotlXattrName("n", 1, 2, 3).WithHide()},
+		{"indx", "scoutfs.indx.time.255.100", IndexXattrName("time", 255, 100)},
+		{"indx no name", "scoutfs.indx.1.2", IndexXattrName("", 1, 2)},
+		{"hidden indx", "scoutfs.hide.indx.t.0.0",
+			IndexXattrName("t", 0, 0).WithHide()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := ParseXattrName(tt.s)
+			if err != nil {
+				t.Fatalf("ParseXattrName(%q): %v", tt.s, err)
+			}
+			if x != tt.want {
+				t.Errorf("ParseXattrName(%q) = %+v, want %+v", tt.s, x, tt.want)
+			}
+			if s := x.String(); s != tt.s {
+				t.Errorf("String() = %q, want %q", s, tt.s)
+			}
+			if err := x.Validate(); err != nil {
+				t.Errorf("Validate() = %v", err)
+			}
+		})
+	}
+}
+
+func TestParseXattrNameTagOrder(t *testing.T) {
+	x, err := ParseXattrName("scoutfs.srch.hide.tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := XattrName{Hide: true, Search: true, Name: "tag"}
+	if x != want {
+		t.Errorf("ParseXattrName() = %+v, want %+v", x, want)
+	}
+}
+
+func TestParseXattrNameErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{"not scoutfs", "user.data"},
+		{"prefix only", "scoutfs."},
+		{"tags only", "scoutfs.hide."},
+		{"duplicate tag", "scoutfs.hide.hide.data"},
+		{"totl and indx", "scoutfs.totl.indx.n.1.2.3"},
+		{"totl missing ids", "scoutfs.totl.n.1.2"},
+		{"totl bad id", "scoutfs.totl.n.1.x.3"},
+		{"totl id range", "scoutfs.totl.n.1.2.18446744073709551616"},
+		{"indx missing ids", "scoutfs.indx.2"},
+		{"indx major range", "scoutfs.indx.t.256.1"},
+		{"too long", "scoutfs.hide." + strings.Repeat("a", XattrNameMax)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := ParseXattrName(tt.s)
+			if err == nil {
+				t.Errorf("ParseXattrName(%q) = %+v, want error", tt.s, x)
+			}
+		})
+	}
+}
+
+func TestXattrNameValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		x       XattrName
+		wantErr bool
+	}{
+		{"hidden", HiddenXattrName("data"), false},
+		{"totl no name", TotlXattrName("", 1, 2, 3), false},
+		{"empty", XattrName{Hide: true}, true},
+		{"totl and indx", XattrName{Totl: true, Index: true, Name: "n"}, true},
+		{"nul", HiddenXattrName("a\x00b"), true},
+		{"max length", HiddenXattrName(strings.Repeat("a",
+			XattrNameMax-len(xattrPrefix+tagHide))), false},
+		{"too long", HiddenXattrName(strings.Repeat("a",
+			XattrNameMax-len(xattrPrefix+tagHide)+1)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.x.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2026 Versity Software, Inc.
+//
+// Use of this source code is governed by a BSD-3-Clause license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package scoutfs
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// SetTaggedXattr validates and sets a scoutfs tagged xattr on file handle.
+// Totl xattrs must have a decimal uint64 value.
+func SetTaggedXattr(f *os.File, x XattrName, value []byte) error {
+	err := x.Validate()
+	if err != nil {
+		return err
+	}
+	if len(value) >= xattrValueMax {
+		return fmt.Errorf("xattr value size %v exceeds %v",
+			len(value), xattrValueMax-1)
+	}
+	if x.Totl {
+		_, err = strconv.ParseUint(string(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("totl value %q must be a decimal uint64", value)
+		}
+	}
+
+	return fsetxattr(f, x.String(), value, 0)
+}
+
+// RemoveTaggedXattr removes a scoutfs tagged xattr from file handle
+func RemoveTaggedXattr(f *os.File, x XattrName) error {
+	err := x.Validate()
+	if err != nil {
+		return err
+	}
+	return fremovexattr(f, x.String())
+}
+
+// SetTotlXattr adds value to the totals for id on file handle
+func SetTotlXattr(f *os.File, id1, id2, id3, value uint64) error {
+	return SetTaggedXattr(f, TotlXattrName("", id1, id2, id3),
+		[]byte(strconv.FormatUint(value, 10)))
+}
+
+// RemoveTotlXattr removes the value for id from the totals on file handle
+func RemoveTotlXattr(f *os.File, id1, id2, id3 uint64) error {
+	return RemoveTaggedXattr(f, TotlXattrName("", id1, id2, id3))
+}
+
+// SetIndexXattr adds file handle to the index for major at minor
+func SetIndexXattr(f *os.File, major uint8, minor uint64) error {
+	return SetTaggedXattr(f, IndexXattrName("", major, minor).WithHide(), nil)
+}
+
+// RemoveIndexXattr removes file handle from the index for major at minor
+func RemoveIndexXattr(f *os.File, major uint8, minor uint64) error {
+	return RemoveTaggedXattr(f, IndexXattrName("", major, minor).WithHide())
+}
+
+// AddSearchTag adds file handle to the search results for name.  The
+// search key for XattrQuery is SearchXattrName(name).String().
+func AddSearchTag(f *os.File, name string) error {
+	return SetTaggedXattr(f, SearchXattrName(name), nil)
+}
+
+// RemoveSearchTag removes file handle from the search results for name
+func RemoveSearchTag(f *os.File, name string) error {
+	return RemoveTaggedXattr(f, SearchXattrName(name))
+}